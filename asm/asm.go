@@ -0,0 +1,662 @@
+// asm assembles 8080 source code written with Intel mnemonics into machine code.
+//
+// Sources hold a statement per line, made of an optional label, an instruction or directive, and a comment:
+//
+//	LOOP:   MVI A,'A'       ; labels end with a colon, which can be left out at the first column
+//	        JMP LOOP
+//
+// The instructions are those described by package isa. Their operands are registers, like A, M or PSW, and
+// expressions of numbers, characters, symbols and the location counter $, described in eval. These directives are
+// understood:
+//
+//	ORG expr             places the following code at the given address
+//	NAME EQU expr        defines a symbol, which can't be redefined
+//	NAME SET expr        defines a symbol that can be redefined later
+//	DB expr|'string',... defines bytes, with strings quoted with ' or "
+//	DW expr,...          defines 16-bit words, stored little-endian
+//	DS expr              reserves the given number of bytes, without defining their contents
+//	END                  ends the source
+//
+// Macros are defined with MACRO, and called by name with the arguments replacing their parameters. The labels
+// declared with LOCAL get a unique name in each expansion, and & concatenates parameters with the text around them:
+//
+//	MOVE    MACRO SRC,DST,N  ; copies N bytes from SRC to DST
+//	        LOCAL LOOP
+//	        LXI H,SRC
+//	        ...
+//	LOOP:   MOV A,M
+//	        ...
+//	        ENDM
+//
+// Errors in the lines of a macro are reported at the line of its body, followed by the chain of calls expanding it.
+//
+// These directives control which lines are assembled, and nest:
+//
+//	REPT expr ... ENDM   repeats the lines up to ENDM the given number of times
+//	IF expr ... ENDIF    assembles the lines up to ENDIF when the expression isn't 0, or those following ELSE if any
+//	INCLUDE file         assembles the given source file, found relative to the one including it
+//	INCBIN file          defines the bytes of the given binary file
+//
+// Sources are assembled in several passes, so symbols can be used before being defined, except in the expressions of
+// ORG, DS, REPT and IF, which tell which code is assembled and where.
+//
+// Programs can also be split into modules assembled separately, and linked with package link. These directives make
+// a source relocatable:
+//
+//	CSEG                 assembles the following code in the code segment, placed by the linker
+//	DSEG                 assembles the following code in the data segment, placed by the linker
+//	ASEG                 assembles the following code at absolute addresses, as by default
+//	PUBLIC name,...      makes the given symbols available to other modules
+//	EXTRN name,...       declares symbols defined in other modules
+//
+// Each segment keeps its own location counter, and ORG sets the offset from its start in the code and data ones.
+// Their labels, and the external symbols, are relocated by the linker in the 16-bit operands of instructions and
+// DW, as described in expr.
+package asm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/miguelff/8080/obj"
+	"github.com/miguelff/8080/symbols"
+)
+
+// Error is an error found in a line of the source
+type Error struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorList are the errors found in a source, in the order they were found
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	lines := make([]string, len(l))
+	for i, err := range l {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Segment is a run of contiguous bytes of a program
+type Segment = obj.Segment
+
+// Program is the machine code of an assembled source
+type Program struct {
+	// Segments are the runs of contiguous bytes of the program, in ascending order of address. The bytes reserved
+	// with DS, and those skipped with ORG, separate them.
+	Segments []Segment
+	// Symbols are the labels of the source, and the symbols defined with EQU and SET. Those of relocatable programs
+	// hold their offset from the start of their segment.
+	Symbols *symbols.Table
+	// Relocatable tells whether the source uses CSEG, DSEG, PUBLIC or EXTRN, so the program must be linked from
+	// its object, and Segments only hold the bytes assembled at absolute addresses
+	Relocatable bool
+
+	object  *obj.Object
+	listing []*listed
+	// rels are the segments the relocatable symbols are relative to
+	rels map[string]obj.Kind
+}
+
+// Object returns the program as a module to link with others
+func (p *Program) Object() *obj.Object {
+	return p.object
+}
+
+// Binary returns the bytes of the program from its lowest address to its highest one, with the gaps between segments
+// filled with zeros, along with the address of the first one
+func (p *Program) Binary() ([]byte, uint16) {
+	if len(p.Segments) == 0 {
+		return nil, 0
+	}
+	first, last := p.Segments[0], p.Segments[len(p.Segments)-1]
+	bin := make([]byte, int(last.Addr)+len(last.Data)-int(first.Addr))
+	for _, s := range p.Segments {
+		copy(bin[s.Addr-first.Addr:], s.Data)
+	}
+	return bin, first.Addr
+}
+
+// Assemble assembles the source read from r. The name of the file it's read from is used to report errors, as an
+// ErrorList.
+func Assemble(name string, r io.Reader) (*Program, error) {
+	lines, err := readLines(name, r)
+	if err != nil {
+		return nil, err
+	}
+	a := &assembler{
+		symbols:  make(map[string]*symbol),
+		images:   make([]image, obj.Data+1),
+		macros:   make(map[string]*macro),
+		files:    map[string][]line{name: lines},
+		binaries: make(map[string][]byte),
+	}
+	// the first passes define the symbols, and are repeated while those referring to symbols defined later get
+	// defined, as in chains of EQU. The last one assembles the code.
+	for {
+		a.pass++
+		a.undefined = false
+		defined := len(a.symbols)
+		a.run(name, lines)
+		if !a.undefined || len(a.symbols) == defined || a.pass == maxPasses {
+			break
+		}
+	}
+	a.pass++
+	a.final = true
+	a.run(name, lines)
+	if len(a.errs) > 0 {
+		a.sortErrors()
+		return nil, a.errs
+	}
+	return a.program(), nil
+}
+
+// AssembleFile assembles the source file at the given path
+func AssembleFile(path string) (*Program, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Assemble(path, f)
+}
+
+// line is a line of source code
+type line struct {
+	file   string
+	number int
+	text   string
+	// expansion is the expansion of the macro the line comes from, if any
+	expansion *expansion
+}
+
+// expansion is the expansion of a macro
+type expansion struct {
+	macro string
+	// call is the line calling the macro
+	call line
+}
+
+// maxExpansions is the number of expansions named in the errors found in macros. Longer chains, like those of
+// recursive macros, are cut in the middle.
+const maxExpansions = 4
+
+// readLines reads the lines of a source, up to the end of the file marker (^Z) of CP/M files, if any
+func readLines(name string, r io.Reader) ([]line, error) {
+	var lines []line
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		text := strings.TrimRight(s.Text(), "\r")
+		if i := strings.IndexByte(text, 0x1A); i >= 0 {
+			lines = append(lines, line{file: name, number: n, text: text[:i]})
+			break
+		}
+		lines = append(lines, line{file: name, number: n, text: text})
+	}
+	return lines, s.Err()
+}
+
+// symbolKind tells how a symbol was defined
+type symbolKind int
+
+const (
+	label symbolKind = iota
+	equ
+	set
+	extern
+)
+
+// symbol is a symbol defined in the source
+type symbol struct {
+	value uint16
+	// rel and ext tell what the value is relative to, as for the values of expressions
+	rel  obj.Kind
+	ext  string
+	kind symbolKind
+	// pass is the pass the symbol was last defined in
+	pass int
+}
+
+// maxPasses is the maximum number of passes defining symbols
+const maxPasses = 10
+
+// assembler holds the state of the assembly of a source
+type assembler struct {
+	// pass is the number of the current pass, and final tells whether it's the one assembling the code
+	pass  int
+	final bool
+	// undefined tells whether a symbol not defined yet was referred to in the current pass
+	undefined bool
+	// pc is the location counter, the address the next byte is assembled at, or its offset in the code or data
+	// segment. seg is the segment being assembled, and pcs the location counters of the others.
+	pc  uint16
+	seg obj.Kind
+	pcs [obj.Data + 1]uint16
+	// wrapped tells whether the location counter of each segment went past 0FFFFH
+	wrapped [obj.Data + 1]bool
+	symbols map[string]*symbol
+	// images hold the bytes assembled in each segment
+	images []image
+	// relocatable tells whether the source uses CSEG, DSEG, PUBLIC or EXTRN
+	relocatable bool
+	// publics, externals and relocs are those declared, in the final pass
+	publics   []string
+	externals []string
+	relocs    []obj.Reloc
+	// sources are the sources being assembled: the file given, and the files included and macros expanded from it
+	sources []*source
+	// conds are the conditional blocks entered
+	conds  []*cond
+	macros map[string]*macro
+	// expansions is the number of macros expanded in the current pass, used to name their local labels
+	expansions int
+	// files are the lines of the files included, and the contents of the binary files included, by path
+	files    map[string][]line
+	binaries map[string][]byte
+	// line is the line being assembled
+	line *line
+	end  bool
+	errs ErrorList
+	// listing are the lines assembled in the final pass
+	listing []*listed
+}
+
+// image holds the bytes assembled in a segment. size is the size of the code and data segments, including the bytes
+// reserved at their end.
+type image struct {
+	memory [0x10000]byte
+	used   [0x10000]bool
+	size   int
+}
+
+// source is a sequence of lines being assembled: a file, or the expansion of a macro or REPT
+type source struct {
+	lines []line
+	next  int
+	// path is the path of the file, for files
+	path string
+	// conds is the number of conditional blocks entered when the source started, which must be closed by its end
+	conds int
+}
+
+// run assembles the lines of the source in the current pass
+func (a *assembler) run(path string, lines []line) {
+	a.pc, a.end, a.expansions, a.conds = 0, false, 0, nil
+	a.seg, a.pcs, a.wrapped = obj.Abs, [obj.Data + 1]uint16{}, [obj.Data + 1]bool{}
+	a.sources = []*source{{lines: lines, path: path}}
+	for len(a.sources) > 0 && !a.end {
+		src := a.sources[len(a.sources)-1]
+		if src.next == len(src.lines) {
+			a.closeSource(src)
+			continue
+		}
+		a.line = &src.lines[src.next]
+		src.next++
+		a.list(*a.line)
+		if err := a.statement(a.line.text); err != nil {
+			a.fail(err)
+		}
+		if img := &a.images[a.seg]; int(a.pc) > img.size {
+			img.size = int(a.pc)
+		}
+	}
+}
+
+// earlyError is an error reported before the final pass, because assembling the rest of the source depends on it
+type earlyError struct {
+	error
+}
+
+func (e earlyError) Unwrap() error {
+	return e.error
+}
+
+// fail reports an error in the current line
+func (a *assembler) fail(err error) {
+	a.failAt(a.line, err)
+}
+
+// failAt reports an error in the given line. Most errors are only reported in the final pass, as those found in
+// the previous ones may be due to symbols defined later.
+func (a *assembler) failAt(l *line, err error) {
+	var early earlyError
+	if !a.final && !errors.As(err, &early) {
+		return
+	}
+	err = expanded(err, l)
+	for _, e := range a.errs {
+		if e.File == l.file && e.Line == l.number && e.Err.Error() == err.Error() {
+			return
+		}
+	}
+	a.errs = append(a.errs, &Error{File: l.file, Line: l.number, Err: err})
+}
+
+// sortErrors sorts the errors in the order of their lines in the source, as assembled in the final pass, as those
+// found in the previous passes are reported first
+func (a *assembler) sortErrors() {
+	type position struct {
+		file   string
+		number int
+	}
+	order := make(map[position]int)
+	for i, l := range a.listing {
+		if _, ok := order[position{l.file, l.number}]; !ok {
+			order[position{l.file, l.number}] = i
+		}
+	}
+	at := func(e *Error) int {
+		if i, ok := order[position{e.File, e.Line}]; ok {
+			return i
+		}
+		return len(a.listing)
+	}
+	sort.SliceStable(a.errs, func(i, j int) bool { return at(a.errs[i]) < at(a.errs[j]) })
+}
+
+// expanded wraps the error found in the given line with the chain of macro expansions the line comes from, like
+// "undefined symbol X, in macro FOO called from main.asm:40"
+func expanded(err error, l *line) error {
+	var chain []*expansion
+	for x := l.expansion; x != nil; x = x.call.expansion {
+		chain = append(chain, x)
+	}
+	for i, x := range chain {
+		switch {
+		case len(chain) > maxExpansions && i == maxExpansions-1:
+			err = fmt.Errorf("%w, ...", err)
+		case len(chain) > maxExpansions && i >= maxExpansions-1 && i < len(chain)-1:
+		default:
+			err = fmt.Errorf("%w, in macro %s called from %s:%d", err, x.macro, x.call.file, x.call.number)
+		}
+	}
+	return err
+}
+
+// directives are the names of the directives, which can't be used as mnemonics
+var directives = map[string]bool{
+	"ORG": true, "EQU": true, "SET": true, "DB": true, "DW": true, "DS": true, "END": true,
+	"MACRO": true, "ENDM": true, "LOCAL": true, "REPT": true, "IF": true, "ELSE": true, "ENDIF": true,
+	"INCLUDE": true, "INCBIN": true, "ASEG": true, "CSEG": true, "DSEG": true, "PUBLIC": true, "EXTRN": true,
+}
+
+// statement assembles a line of the source
+func (a *assembler) statement(text string) error {
+	st, err := parse(text)
+	if a.skipping() {
+		// only the conditionals are followed in the lines skipped, to find where the block ends
+		if err == nil && (st.op == "IF" || st.op == "ELSE" || st.op == "ENDIF") {
+			return a.conditional(st)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch st.op {
+	case "IF", "ELSE", "ENDIF":
+		return a.conditional(st)
+	case "MACRO":
+		return a.defineMacro(st)
+	case "ENDM":
+		return errors.New("ENDM without MACRO or REPT")
+	case "LOCAL":
+		return errors.New("LOCAL outside MACRO")
+	case "ASEG", "CSEG", "DSEG":
+		return a.segment(st)
+	case "PUBLIC", "EXTRN":
+		return a.linkage(st)
+	case "EQU", "SET":
+		if st.label == "" {
+			return fmt.Errorf("%s needs a name", st.op)
+		}
+		if len(st.operands) != 1 {
+			return fmt.Errorf("%s takes 1 operand, got %d", st.op, len(st.operands))
+		}
+		v, err := a.expr(st.operands[0])
+		if err != nil && (a.final || !errors.Is(err, errUndefined)) {
+			return err
+		}
+		kind := equ
+		if st.op == "SET" {
+			kind = set
+		}
+		if err != nil {
+			return nil
+		}
+		if l := a.listed(); l != nil {
+			l.equ, l.defined = v, true
+		}
+		return a.define(st.label, v, kind)
+	}
+
+	if st.label != "" {
+		a.locate(a.pc)
+		if err := a.define(st.label, value{n: int(a.pc), rel: a.seg}, label); err != nil {
+			return err
+		}
+	}
+	if st.op == "" {
+		return nil
+	}
+
+	switch st.op {
+	case "ORG":
+		v, err := a.location(st)
+		if err == nil && v.rel != obj.Abs && v.rel != a.seg {
+			err = earlyError{fmt.Errorf("ORG to a value relative to another segment or symbol")}
+		}
+		a.pc, a.wrapped[a.seg] = uint16(v.n), false
+		a.locate(a.pc)
+		return err
+	case "DS":
+		a.locate(a.pc)
+		v, err := a.location(st)
+		if err == nil && v.rel != obj.Abs {
+			err = earlyError{fmt.Errorf("DS of a relocatable value")}
+		}
+		if int(a.pc)+v.n > 0xFFFF {
+			a.wrapped[a.seg] = true
+		}
+		a.pc += uint16(v.n)
+		return err
+	case "END":
+		a.end = true
+		return nil
+	case "DB":
+		return a.data(st.operands, 1)
+	case "DW":
+		return a.data(st.operands, 2)
+	case "REPT":
+		return a.repeat(st)
+	case "INCLUDE":
+		return a.include(st)
+	case "INCBIN":
+		return a.includeBinary(st)
+	}
+
+	if m, ok := a.macros[st.op]; ok {
+		return a.expand(m, st.operands)
+	}
+	f, ok := forms[st.op]
+	if !ok {
+		return fmt.Errorf("unknown instruction %s", st.op)
+	}
+	bytes, err := a.instruction(st.op, f, st.operands)
+	if err != nil {
+		// keep the size of the instruction, so the following labels get their right addresses
+		bytes = make([]byte, f.size)
+	}
+	return firstErr(err, a.emit(bytes...))
+}
+
+// location evaluates the single operand of ORG or DS. It can only refer to the symbols already defined, as the
+// following code is placed after its value.
+func (a *assembler) location(st statement) (value, error) {
+	if len(st.operands) != 1 {
+		return value{}, fmt.Errorf("%s takes 1 operand, got %d", st.op, len(st.operands))
+	}
+	v, err := a.expr(st.operands[0])
+	if err != nil {
+		return v, earlyError{err}
+	}
+	return v, nil
+}
+
+// data assembles the operands of DB, with a size of 1, or DW, with a size of 2
+func (a *assembler) data(operands []string, size int) error {
+	if len(operands) == 0 {
+		return errors.New("missing data")
+	}
+	var err error
+	for _, operand := range operands {
+		if size == 1 && len(operand) > 0 && (operand[0] == '\'' || operand[0] == '"') {
+			if s, n, qerr := unquote(operand); qerr == nil && n == len(operand) && len(s) != 1 {
+				err = firstErr(err, a.emit([]byte(s)...))
+				continue
+			}
+		}
+
+		if size == 1 {
+			v, verr := a.eval(operand)
+			b, berr := toByte(v)
+			err = firstErr(err, verr, berr, a.emit(b))
+			continue
+		}
+		v, verr := a.expr(operand)
+		w, werr := toWord(v.n)
+		a.relocate(a.pc, v)
+		err = firstErr(err, verr, werr, a.emit(byte(w), byte(w>>8)))
+	}
+	return err
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emit assembles the given bytes at the location counter, and advances it. Bytes can't be assembled over others, or
+// past 0FFFFH.
+func (a *assembler) emit(bytes ...byte) error {
+	a.locate(a.pc)
+	if l := a.listed(); l != nil {
+		l.bytes = append(l.bytes, bytes...)
+	}
+	img := &a.images[a.seg]
+	var err error
+	for _, b := range bytes {
+		if a.wrapped[a.seg] {
+			return errors.New("location counter past 0FFFFH")
+		}
+		if a.final {
+			if img.used[a.pc] && err == nil {
+				err = fmt.Errorf("overlaps code at %04XH", a.pc)
+			}
+			img.memory[a.pc] = b
+			img.used[a.pc] = true
+		}
+		a.pc++
+		a.wrapped[a.seg] = a.pc == 0
+	}
+	return err
+}
+
+// define defines a symbol of the given kind. Only SET symbols can be redefined, and labels can't move between passes.
+func (a *assembler) define(name string, v value, kind symbolKind) error {
+	if isReserved(name) {
+		return fmt.Errorf("%s is a reserved word", name)
+	}
+	s, ok := a.symbols[name]
+	switch {
+	case !ok:
+		a.symbols[name] = &symbol{value: uint16(v.n), rel: v.rel, ext: v.ext, kind: kind, pass: a.pass}
+		return nil
+	case kind == set && s.kind == set:
+	case s.pass == a.pass:
+		return fmt.Errorf("%s redefined", name)
+	case kind == label && (s.value != uint16(v.n) || s.rel != v.rel):
+		return fmt.Errorf("label %s moved from %04XH to %04XH between passes", name, s.value, uint16(v.n))
+	}
+	s.value, s.rel, s.ext, s.kind, s.pass = uint16(v.n), v.rel, v.ext, kind, a.pass
+	return nil
+}
+
+// value returns the value of the symbol with the given name. The symbols defined in a previous pass but not yet in
+// the current one keep their value, except for those defined with SET in the final pass. The rest are reported
+// with errUndefined.
+func (a *assembler) value(name string) (value, error) {
+	s, ok := a.symbols[name]
+	if !ok || (a.final && s.pass != a.pass && s.kind == set) {
+		a.undefined = true
+		return value{}, fmt.Errorf("%w %s", errUndefined, name)
+	}
+	return value{n: int(s.value), rel: s.rel, ext: s.ext}, nil
+}
+
+// program returns the program assembled
+func (a *assembler) program() *Program {
+	p := &Program{
+		Symbols:     symbols.New(),
+		Relocatable: a.relocatable,
+		listing:     a.listing,
+		rels:        make(map[string]obj.Kind),
+	}
+	p.Segments = a.images[obj.Abs].runs()
+
+	names := make([]string, 0, len(a.symbols))
+	for name, s := range a.symbols {
+		if s.kind != extern {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := a.symbols[name]
+		p.Symbols.Add(name, s.value)
+		if s.rel != obj.Abs {
+			p.rels[name] = s.rel
+		}
+	}
+	p.object = a.object(p.Segments)
+	return p
+}
+
+// runs returns the runs of contiguous bytes assembled in the image, in ascending order of address
+func (img *image) runs() []Segment {
+	var segments []Segment
+	for addr := 0; addr < len(img.used); addr++ {
+		if !img.used[addr] {
+			continue
+		}
+		end := addr
+		for end < len(img.used) && img.used[end] {
+			end++
+		}
+		segments = append(segments, Segment{Addr: uint16(addr), Data: append([]byte(nil), img.memory[addr:end]...)})
+		addr = end
+	}
+	return segments
+}
@@ -0,0 +1,497 @@
+package asm
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miguelff/8080/dasm"
+	"github.com/miguelff/8080/encoding"
+	"github.com/miguelff/8080/obj"
+	"github.com/miguelff/8080/symbols"
+)
+
+func TestAssemble(t *testing.T) {
+	for _, tC := range []struct {
+		desc   string
+		source string
+		want   string
+		origin uint16
+	}{
+		{
+			"instructions",
+			"\tMOV A,M\n\tmvi b,10\n\tLXI SP,2400H\n\tPUSH PSW\n\tRST 7\n\tIN 1\n\tJMP 1234H",
+			"7e 06 0a 31 00 24 f5 ff db 01 c3 34 12",
+			0,
+		},
+		{
+			"labels and forward references",
+			"\tORG 100H\nSTART:\tCALL SUBR\nLOOP\tJMP LOOP\n  SUBR:\n\tRET",
+			"cd 06 01 c3 03 01 c9",
+			0x100,
+		},
+		{
+			"symbols",
+			"COUNT\tEQU 3\nN\tSET 1\nN\tSET N+COUNT\n\tMVI A,N\n\tLXI H,DONE-$+1\nDONE:",
+			"3e 04 21 04 00",
+			0,
+		},
+		{
+			"data",
+			"\tDB 1,'A',\"BC\",'it''s';comment\n\tDW 1234H,LABEL\n\tDS 2\nLABEL:\tDB -1",
+			"01 41 42 43 69 74 27 73 34 12 0e 00 00 00 ff",
+			0,
+		},
+		{
+			"numbers",
+			"\tDB 0FFH,377Q,377O,1010B,$FF,10D,10,'A'\n\tDW 'AB',0ffffh",
+			"ff ff ff 0a ff 0a 0a 41 42 41 ff ff",
+			0,
+		},
+		{
+			"operators",
+			"\tDB 1+2*3,(1+2)*3,7/2,7 MOD 2,1 SHL 4,80H SHR 3,-1,NOT 0\n" +
+				"\tDB 0CH AND 0AH,0CH OR 0AH,0CH XOR 0AH,1 or 2 and 3,NOT 1 AND 3\n" +
+				"\tDB HIGH 1234H,LOW(1234H),HIGH 1234H+1,LOW -1",
+			"07 09 03 01 10 10 ff ff 08 0e 06 03 02 12 34 13 ff",
+			0,
+		},
+		{
+			"comparisons",
+			"\tDW 1 EQ 1,1 NE 1,1 LT 2,2 LE 1,-1 GT 1,1 GE 1,1+1 EQ 2",
+			"ff ff 00 00 ff ff 00 00 ff ff ff ff ff ff",
+			0,
+		},
+		{
+			"location counter",
+			"\tORG 100H\n\tNOP\n\tJMP $+3\n\tDW $",
+			"00 c3 04 01 04 01",
+			0x100,
+		},
+		{
+			"forward EQU chains",
+			"\tLXI H,A\nA\tEQU B+1\nB\tEQU C\nC\tEQU 5\nMASK\tEQU (A SHL 8) OR LOW B\n\tLXI D,MASK",
+			"21 06 00 11 05 06",
+			0,
+		},
+		{
+			"end",
+			"\tNOP\n\tEND\n\tHLT",
+			"00",
+			0,
+		},
+		{
+			"macros",
+			"FILL\tMACRO ADDR,N\n\tLOCAL LOOP\n\tLXI H,ADDR\n\tMVI B,N\nLOOP:\tMOV M,A\n\tINX H\n" +
+				"\tDCR B\n\tJNZ LOOP\n\tENDM\n" +
+				"\tFILL 2400H,10\n\tFILL 2500H,20",
+			"21 00 24 06 0a 77 23 05 c2 05 00 21 00 25 06 14 77 23 05 c2 10 00",
+			0,
+		},
+		{
+			"nested macros and concatenation",
+			"LOAD\tMACRO R,V\n\tMVI R,V\n\tENDM\n" +
+				"PAIR\tMACRO X\n\tLOAD X,1\n\tLOAD X,X&X ; AA\n\tENDM\n" +
+				"AA\tEQU 3\n\tPAIR A",
+			"3e 01 3e 03",
+			0,
+		},
+		{
+			"missing macro arguments are empty",
+			"OP\tMACRO I,R\n\tI R\n\tENDM\n\tOP NOP",
+			"00",
+			0,
+		},
+		{
+			"rept",
+			"\tREPT 3\n\tNOP\n\tREPT 2\n\tHLT\n\tENDM\n\tENDM",
+			"00 76 76 00 76 76 00 76 76",
+			0,
+		},
+		{
+			"conditionals",
+			"DEBUG\tEQU 1\n" +
+				"\tIF DEBUG\n\tNOP\n\tIF DEBUG-1\n\tHLT\n\tELSE\n\tRET\n\tENDIF\n\tELSE\n" +
+				"\tIF 1\n\tHLT\n\tELSE\n\tHLT\n\tENDIF\n\tFOO ; not assembled\n\tENDIF",
+			"00 c9",
+			0,
+		},
+		{
+			"up to 0FFFFH",
+			"\tORG 0FFFDH\n\tJMP 0",
+			"c3 00 00",
+			0xFFFD,
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			p, err := Assemble("test.asm", strings.NewReader(tC.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			bin, origin := p.Binary()
+			if want := encoding.MustParseHex(tC.want); !bytes.Equal(bin, want) || origin != tC.origin {
+				t.Errorf("got % X at %04X, want % X at %04X", bin, origin, want, tC.origin)
+			}
+		})
+	}
+}
+
+func TestAssemble_Segments(t *testing.T) {
+	p, err := Assemble("test.asm", strings.NewReader("\tORG 10H\n\tNOP\n\tDS 2\n\tHLT\n\tORG 0\n\tRET"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Segments) != 3 || p.Segments[0].Addr != 0 || p.Segments[1].Addr != 0x10 || p.Segments[2].Addr != 0x13 {
+		t.Errorf("got segments %+v", p.Segments)
+	}
+}
+
+func TestAssembleFile_Include(t *testing.T) {
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"main.asm":     "\tINCLUDE lib/defs.asm\n\tMVI A,VALUE\n\tINCBIN 'lib/data.bin'\n",
+		"lib/defs.asm": "VALUE\tEQU 42\n\tIF 0\n\tINCLUDE missing.asm\n\tENDIF\n",
+		"lib/data.bin": "\x01\x02",
+		"self.asm":     "\tNOP\n\tINCLUDE self.asm\n",
+		"open.asm":     "\tINCLUDE lib/open.asm\n\tENDIF\n",
+		"lib/open.asm": "\tIF 1\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tC := range []struct {
+		file    string
+		want    string
+		wantErr string
+	}{
+		{"main.asm", "3e 2a 01 02", ""},
+		{"self.asm", "", "self.asm:2: " + filepath.Join(dir, "self.asm") + " includes itself"},
+		{"open.asm", "", "lib/open.asm:1: IF without ENDIF\n" + filepath.Join(dir, "open.asm") + ":2: ENDIF without IF"},
+	} {
+		t.Run(tC.file, func(t *testing.T) {
+			p, err := AssembleFile(filepath.Join(dir, tC.file))
+			if tC.wantErr != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), tC.wantErr) {
+					t.Errorf("got error %v, want %q", err, tC.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bin, _ := p.Binary(); !bytes.Equal(bin, encoding.MustParseHex(tC.want)) {
+				t.Errorf("got % X, want %s", bin, tC.want)
+			}
+		})
+	}
+}
+
+func TestAssemble_Relocatable(t *testing.T) {
+	source := "\tEXTRN PRINT\n\tPUBLIC START,BUF\n" +
+		"\tCSEG\nSTART:\tLXI H,MSG\n\tCALL PRINT+3\n\tLXI D,BUF\n\tMVI B,MSGEND-MSG\nMSG:\tDB 'HI'\nMSGEND:\n" +
+		"\tDSEG\n\tDS 1\nBUF:\tDS 16\n" +
+		"\tASEG\n\tORG 0\n\tJMP START\n" +
+		"\tCSEG\n\tDW BUF+1"
+	p, err := Assemble("test.asm", strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Relocatable {
+		t.Error("got an absolute program, want a relocatable one")
+	}
+
+	o := p.Object()
+	if want := encoding.MustParseHex("21 0b 00 cd 03 00 11 01 00 06 02 48 49 02 00"); o.Code.Size != 15 ||
+		len(o.Code.Bytes) != 1 || !bytes.Equal(o.Code.Bytes[0].Data, want) {
+		t.Errorf("got code %+v, want % X", o.Code, want)
+	}
+	if o.Data.Size != 17 || len(o.Data.Bytes) != 0 {
+		t.Errorf("got data %+v, want 17 bytes undefined", o.Data)
+	}
+	if len(o.Abs) != 1 || o.Abs[0].Addr != 0 || !bytes.Equal(o.Abs[0].Data, encoding.MustParseHex("c3 00 00")) {
+		t.Errorf("got absolute segments %+v", o.Abs)
+	}
+	wantPublics := []obj.Symbol{{Name: "START", Kind: obj.Code, Value: 0}, {Name: "BUF", Kind: obj.Data, Value: 1}}
+	if !reflect.DeepEqual(o.Publics, wantPublics) || !reflect.DeepEqual(o.Externals, []string{"PRINT"}) {
+		t.Errorf("got publics %+v and externals %v", o.Publics, o.Externals)
+	}
+	wantRelocs := []obj.Reloc{
+		{Kind: obj.Code, Offset: 0x01, To: obj.Code},
+		{Kind: obj.Code, Offset: 0x04, To: obj.External, Ext: "PRINT"},
+		{Kind: obj.Code, Offset: 0x07, To: obj.Data},
+		{Kind: obj.Abs, Offset: 0x01, To: obj.Code},
+		{Kind: obj.Code, Offset: 0x0D, To: obj.Data},
+	}
+	if !reflect.DeepEqual(o.Relocs, wantRelocs) {
+		t.Errorf("got relocations %+v, want %+v", o.Relocs, wantRelocs)
+	}
+}
+
+func TestProgram_WriteListing(t *testing.T) {
+	source := "\tORG 100H\nN\tEQU 3\nM\tMACRO\n\tMVI A,N\n\tENDM\nSTART:\tM\n\tDB 'HELLO',0\n\tIF 0\n\tNOP\n\tENDIF"
+	p, err := Assemble("test.asm", strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var listing bytes.Buffer
+	if err := p.WriteListing(&listing); err != nil {
+		t.Fatal(err)
+	}
+	want := "0100                  1  \tORG 100H\n" +
+		"      = 0003          2  N\tEQU 3\n" +
+		"                      3  M\tMACRO\n" +
+		"                      4  \tMVI A,N\n" +
+		"                      5  \tENDM\n" +
+		"0100                  6  START:\tM\n" +
+		"0100  3E 03           4+ \tMVI A,N\n" +
+		"0102  48 45 4C 4C     7  \tDB 'HELLO',0\n" +
+		"0106  4F 00\n" +
+		"                      8  \tIF 0\n" +
+		"                      9  \tNOP\n" +
+		"                     10  \tENDIF\n" +
+		"\n" +
+		"0003 N               0100 START\n"
+	if got := listing.String(); got != want {
+		t.Errorf("got listing\n%s\nwant\n%s", got, want)
+	}
+
+	table, err := symbols.ReadListing(&listing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr, ok := table.Addr("START"); !ok || addr != 0x100 {
+		t.Errorf("got START at %04X, want 0100", addr)
+	}
+	for addr, want := range map[uint16]string{0x100: "4  \tMVI A,N", 0x102: "7  \tDB 'HELLO',0"} {
+		if got, _ := table.Line(addr); got != want {
+			t.Errorf("%04X: got line %q, want %q", addr, got, want)
+		}
+	}
+	if line, ok := table.Line(0x106); ok {
+		t.Errorf("0106: got line %q, want none", line)
+	}
+}
+
+func TestProgram_WriteListing_Relocatable(t *testing.T) {
+	source := "\tCSEG\nSTART:\tLXI H,MSG\n\tDSEG\nMSG:\tDB 'HI'\n\tASEG\nRESET:\tJMP START"
+	p, err := Assemble("test.asm", strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var listing, sym bytes.Buffer
+	if err := p.WriteListing(&listing); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.WriteSymbols(&sym); err != nil {
+		t.Fatal(err)
+	}
+	want := "0000\" MSG            0000 RESET           0000' START\n"
+	if got := listing.String(); !strings.HasSuffix(got, "\n\n"+want) {
+		t.Errorf("got listing\n%s\nwant the symbols\n%s", got, want)
+	}
+	if got, want := sym.String(), "0000 RESET\n"; got != want {
+		t.Errorf("got symbols %q, want %q", got, want)
+	}
+
+	table, err := symbols.ReadListing(&listing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(table.Names(), " "); got != "RESET" {
+		t.Errorf("got symbols %s from the listing, want RESET", got)
+	}
+	if got, _ := table.Line(0); got != "6  RESET:\tJMP START" {
+		t.Errorf("got line %q at 0000, want %q", got, "6  RESET:\tJMP START")
+	}
+}
+
+func TestProgram_WriteLineMap(t *testing.T) {
+	source := "\tORG 100H\nM\tMACRO\n\tMVI A,3\n\tENDM\nSTART:\tM\n\tDB 'HI'\n\tCSEG\n\tNOP"
+	p, err := Assemble("src/test.asm", strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines bytes.Buffer
+	if err := p.WriteLineMap(&lines, "src"); err != nil {
+		t.Fatal(err)
+	}
+	want := "; address file:line\n" +
+		"0100 test.asm:3\n" +
+		"0102 test.asm:6\n"
+	if got := lines.String(); got != want {
+		t.Errorf("got line map\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestAssemble_Errors(t *testing.T) {
+	for _, tC := range []struct {
+		desc   string
+		source string
+		want   string
+	}{
+		{"unknown instruction", "\tNOP\n\tFOO A", "test.asm:2: unknown instruction FOO"},
+		{"undefined symbol", "\tJMP NOWHERE", "test.asm:1: undefined symbol NOWHERE"},
+		{"invalid register", "\tMOV A,X", "test.asm:1: invalid registers A,X for MOV"},
+		{"operand count", "\tMVI A", "test.asm:1: MVI takes 2 operands, got 1"},
+		{"byte out of range", "\tMVI A,256", "test.asm:1: value 256 out of range for a byte"},
+		{"division by zero", "\tMVI A,1/(2-2)", "test.asm:1: division by zero"},
+		{"invalid number", "\tMVI A,12G", "test.asm:1: invalid number 12G"},
+		{"word out of range", "\tDW 10000H", "test.asm:1: value 65536 out of range for a word"},
+		{"number out of range", "\tDW 100000000H", "test.asm:1: number 100000000H out of range"},
+		{"overlapping code", "\tORG 0\n\tDB 1\n\tORG 0\n\tDB 2", "test.asm:4: overlaps code at 0000H"},
+		{"overlapping instruction", "\tORG 100H\n\tJMP 0\n\tORG 102H\n\tNOP", "test.asm:4: overlaps code at 0102H"},
+		{"past 0FFFFH", "\tORG 0FFFEH\n\tDB 1\n\tLXI H,0", "test.asm:3: location counter past 0FFFFH"},
+		{"reserved past 0FFFFH", "\tORG 0FFFEH\n\tDS 2\n\tNOP", "test.asm:3: location counter past 0FFFFH"},
+		{"missing parenthesis", "\tMVI A,(1+2", "test.asm:1: missing ) in expression \"(1+2\""},
+		{"missing operand", "\tMVI A,1+", "test.asm:1: missing operand in expression \"1+\""},
+		{"long character constant", "\tLXI H,'ABC'", "test.asm:1: character constant 'ABC' must hold 1 or 2 characters"},
+		{"operator as name", "AND:\tNOP", "test.asm:1: AND is a reserved word"},
+		{"circular EQU", "A\tEQU B\nB\tEQU A", "test.asm:1: undefined symbol B\ntest.asm:2: undefined symbol A"},
+		{"relocatable byte", "\tCSEG\nX:\tMVI A,X", "test.asm:2: relocatable value X where an absolute one is needed"},
+		{"high of relocatable", "\tEXTRN X\n\tMVI A,HIGH X", "test.asm:2: HIGH of a relocatable value"},
+		{"sum of relocatables", "\tCSEG\nX:\n\tDSEG\nY:\tDW X+Y", "test.asm:4: + of values relative to different segments or symbols"},
+		{"undefined public", "\tPUBLIC X", "test.asm:1: undefined symbol X"},
+		{"external public", "\tEXTRN X\n\tPUBLIC X", "test.asm:2: X is external"},
+		{"external redefined", "\tEXTRN X\nX:\tNOP", "test.asm:2: X redefined"},
+		{"redefined label", "X:\tNOP\nX:\tNOP", "test.asm:2: X redefined"},
+		{"redefined EQU", "X\tEQU 1\nX\tEQU 2", "test.asm:2: X redefined"},
+		{"forward ORG", "\tORG LATER\nLATER:", "test.asm:1: undefined symbol LATER"},
+		{"unterminated string", "\tDB 'abc", "test.asm:1: unterminated string 'abc"},
+		{"reserved word", "MOV:\tNOP", "test.asm:1: MOV is a reserved word"},
+		{"unterminated macro", "M\tMACRO\n\tNOP", "test.asm:1: MACRO without ENDM"},
+		{"unterminated rept", "\tNOP\n\tREPT 2\n\tNOP", "test.asm:2: REPT without ENDM"},
+		{"unterminated if", "\tIF 1\n\tIF 0\n\tENDIF", "test.asm:1: IF without ENDIF"},
+		{"endm without macro", "\tNOP\n\tENDM", "test.asm:2: ENDM without MACRO or REPT"},
+		{"endif without if", "\tENDIF", "test.asm:1: ENDIF without IF"},
+		{"else after else", "\tIF 1\n\tELSE\n\tELSE\n\tENDIF", "test.asm:3: ELSE after ELSE"},
+		{"forward if", "\tIF LATER\n\tENDIF\nLATER:", "test.asm:1: undefined symbol LATER"},
+		{"error in macro", "M\tMACRO\n\tFOO\n\tENDM\n\tNOP\n\tM", "test.asm:2: unknown instruction FOO, in macro M called from test.asm:5"},
+		{
+			"error in nested macro",
+			"INNER\tMACRO R\n\tMVI R,1\n\tENDM\nOUTER\tMACRO\n\tNOP\n\tINNER X\n\tENDM\n\tOUTER",
+			"test.asm:2: invalid registers X for MVI, in macro INNER called from test.asm:6, in macro OUTER called from test.asm:8",
+		},
+		{"macro arguments", "M\tMACRO X\n\tENDM\n\tM 1,2", "test.asm:3: macro M takes 1 arguments, got 2"},
+		{"recursive macro", "M\tMACRO\n\tM\n\tENDM\n\tM", "test.asm:2: more than 64 nested macros and includes, in macro M called from test.asm:2, " +
+			"in macro M called from test.asm:2, in macro M called from test.asm:2, ..., in macro M called from test.asm:4"},
+		{
+			"several errors",
+			"\tFOO\n\tNOP\n\tBAR",
+			"test.asm:1: unknown instruction FOO\ntest.asm:3: unknown instruction BAR",
+		},
+		{"operand and overlap", "\tNOP\n\tORG 0\n\tMVI A,100H", "test.asm:3: value 256 out of range for a byte"},
+		{"data and overlap", "\tNOP\n\tORG 0\n\tDB 100H", "test.asm:3: value 256 out of range for a byte"},
+		{"early error after another", "\tFOO\n\tIF 1", "test.asm:1: unknown instruction FOO\ntest.asm:2: IF without ENDIF"},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := Assemble("test.asm", strings.NewReader(tC.source))
+			if err == nil || err.Error() != tC.want {
+				t.Errorf("got error %v, want %q", err, tC.want)
+			}
+		})
+	}
+}
+
+// reassemble disassembles the machine code into source code, and assembles it back
+func reassemble(t *testing.T, code []byte) (p *Program, bin []byte, source string) {
+	t.Helper()
+	var w bytes.Buffer
+	if err := dasm.DisassembleWith(bytes.NewReader(code), &w, 0, dasm.Options{Reassemble: true}); err != nil {
+		t.Fatal(err)
+	}
+	source = w.String()
+	p, err := Assemble("test.asm", strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("%v\nsource:\n%s", err, source)
+	}
+	bin, origin := p.Binary()
+	if origin != 0 {
+		t.Errorf("got origin %04X, want 0000", origin)
+	}
+	return p, bin, source
+}
+
+// instructionBytes returns the number of bytes of the program assembled by instructions, rather than by DB and DW
+func instructionBytes(p *Program) int {
+	n := 0
+	for _, l := range p.listing {
+		// the labels written by the disassembler start the line, and end with a colon
+		text := l.text
+		if i := strings.IndexByte(text, ':'); i >= 0 && !strings.HasPrefix(text, "\t") {
+			text = text[i+1:]
+		}
+		if fields := strings.Fields(text); len(fields) > 0 && fields[0] != "DB" && fields[0] != "DW" {
+			n += len(l.bytes)
+		}
+	}
+	return n
+}
+
+func TestRoundTrip_Invaders(t *testing.T) {
+	rom, err := ioutil.ReadFile("../cmd/invaders.rom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, bin, _ := reassemble(t, rom)
+	if !bytes.Equal(bin, rom) {
+		for i := range rom {
+			if i >= len(bin) || bin[i] != rom[i] {
+				t.Fatalf("got %d bytes, want %d, differing first at %04X", len(bin), len(rom), i)
+			}
+		}
+		t.Fatalf("got %d bytes, want %d", len(bin), len(rom))
+	}
+
+	// the code reached by the flow analysis must be written as instructions, not as data
+	cfg, err := dasm.BuildCFG(rom, dasm.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := 0
+	for _, b := range cfg.Blocks {
+		code += int(b.End() - b.Start)
+	}
+	if got := instructionBytes(p); got != code || code < len(rom)/4 {
+		t.Errorf("got %d bytes written as instructions, want the %d bytes of code of %d", got, code, len(rom))
+	}
+}
+
+func TestRoundTrip_Opcodes(t *testing.T) {
+	rnd := rand.New(rand.NewSource(8080))
+	for op := 0; op < 256; op++ {
+		for i := 0; i < 8; i++ {
+			code := []byte{byte(op), byte(rnd.Intn(256)), byte(rnd.Intn(256))}
+			inst, err := dasm.Decode(code, 0)
+			if err == nil {
+				code = code[:inst.Size]
+			} else {
+				code = code[:1]
+			}
+
+			_, bin, source := reassemble(t, code)
+			if !bytes.Equal(bin, code) {
+				t.Errorf("% X: got % X from source\n%s", code, bin, source)
+				break
+			}
+			// documented instructions must be written with their mnemonics, not as data
+			if err == nil && strings.Contains(source, "\tDB ") {
+				t.Errorf("% X: written as data\n%s", code, source)
+				break
+			}
+		}
+	}
+}
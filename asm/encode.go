@@ -0,0 +1,116 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miguelff/8080/isa"
+	"github.com/miguelff/8080/obj"
+)
+
+// form describes the operands an instruction takes
+type form struct {
+	// regs is the number of register operands, like 2 for MOV or 1 for LXI
+	regs int
+	// operand is the kind of the operand following the registers, if any
+	operand isa.Operand
+	// size is the number of bytes of the instruction
+	size int
+}
+
+var (
+	// forms are the operands taken by each mnemonic
+	forms = make(map[string]form)
+	// opcodes are the opcodes of the instructions by their mnemonic and register operands, like "MOV A,M" or
+	// "LXI H", or the number of the vector for RST, like "RST 7"
+	opcodes = make(map[string]byte)
+)
+
+// init builds the instruction table from the description of the instruction set, shared with the disassembler
+func init() {
+	for op, inst := range isa.Opcodes {
+		if !inst.Defined() {
+			continue
+		}
+		f := form{operand: inst.Operand, size: inst.Size}
+		if inst.Regs != "" {
+			f.regs = strings.Count(inst.Regs, ",") + 1
+		}
+		forms[inst.Mnemonic] = f
+		opcodes[opcodeKey(inst.Mnemonic, inst.Regs, inst.Operand, uint16(inst.Vector))] = byte(op)
+	}
+}
+
+// opcodeKey returns the key of an instruction in opcodes
+func opcodeKey(mnemonic, regs string, operand isa.Operand, value uint16) string {
+	key := mnemonic
+	if regs != "" {
+		key += " " + regs
+	}
+	if operand == isa.Vector {
+		key += fmt.Sprintf(" %d", value)
+	}
+	return key
+}
+
+// instruction encodes the instruction with the given mnemonic and operands
+func (a *assembler) instruction(mnemonic string, f form, operands []string) ([]byte, error) {
+	want := f.regs
+	if f.operand != isa.NoOperand {
+		want++
+	}
+	if len(operands) != want {
+		return nil, fmt.Errorf("%s takes %d operands, got %d", mnemonic, want, len(operands))
+	}
+
+	regs := make([]string, f.regs)
+	for i := range regs {
+		regs[i] = strings.ToUpper(operands[i])
+	}
+	var v value
+	if f.operand != isa.NoOperand {
+		var err error
+		if v, err = a.expr(operands[f.regs]); err != nil {
+			return nil, err
+		}
+		// only 16-bit operands can be relocated
+		if f.size != 3 && v.rel != obj.Abs {
+			return nil, fmt.Errorf("relocatable value %s where an absolute one is needed", operands[f.regs])
+		}
+	}
+	n := v.n
+	if f.operand == isa.Vector && (n < 0 || n > 7) {
+		return nil, fmt.Errorf("restart vector %d out of range 0-7", n)
+	}
+
+	op, ok := opcodes[opcodeKey(mnemonic, strings.Join(regs, ","), f.operand, uint16(n))]
+	if !ok {
+		return nil, fmt.Errorf("invalid registers %s for %s", strings.Join(regs, ","), mnemonic)
+	}
+	switch f.size {
+	case 2:
+		b, err := toByte(n)
+		return []byte{op, b}, err
+	case 3:
+		w, err := toWord(n)
+		a.relocate(a.pc+1, v)
+		return []byte{op, byte(w), byte(w >> 8)}, err
+	}
+	return []byte{op}, nil
+}
+
+// toByte returns the byte holding the given value, which can be negative
+func toByte(v int) (byte, error) {
+	if v < -0x80 || v > 0xFF {
+		return 0, fmt.Errorf("value %d out of range for a byte", v)
+	}
+	return byte(v), nil
+}
+
+// toWord returns the 16-bit word holding the given value, which can be negative
+func toWord(v int) (uint16, error) {
+	if v < -0x8000 || v > 0xFFFF {
+		return 0, fmt.Errorf("value %d out of range for a word", v)
+	}
+	return uint16(v), nil
+}
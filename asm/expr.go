@@ -0,0 +1,351 @@
+package asm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/miguelff/8080/obj"
+)
+
+// errUndefined is returned when evaluating an expression referring to a symbol not defined yet
+var errUndefined = errors.New("undefined symbol")
+
+// operators are the operators written as words, which can't be used as names
+var operators = map[string]bool{
+	"MOD": true, "SHL": true, "SHR": true, "NOT": true, "AND": true, "OR": true, "XOR": true,
+	"EQ": true, "NE": true, "LT": true, "LE": true, "GT": true, "GE": true, "HIGH": true, "LOW": true,
+}
+
+// true16 is the value of the comparisons that hold: all bits set
+const true16 = 0xFFFF
+
+// value is the result of an expression: a number, which is relative to the start of a segment of the module, or to
+// an external symbol, when rel isn't obj.Abs
+type value struct {
+	n   int
+	rel obj.Kind
+	// ext is the name of the external symbol, for obj.External
+	ext string
+}
+
+// absolute returns an absolute value
+func absolute(n int) value {
+	return value{n: n}
+}
+
+// sameBase tells whether both values are relative to the same thing
+func (v value) sameBase(w value) bool {
+	return v.rel == w.rel && v.ext == w.ext
+}
+
+// eval evaluates an expression whose value must be absolute. See expr.
+func (a *assembler) eval(expr string) (int, error) {
+	v, err := a.expr(expr)
+	if err == nil && v.rel != obj.Abs {
+		err = fmt.Errorf("relocatable value %s where an absolute one is needed", expr)
+	}
+	return v.n, err
+}
+
+// expr evaluates an expression. These are its operators, from the highest precedence to the lowest:
+//
+//	HIGH LOW                 the high and low byte of a 16-bit value, as in HIGH(BUF) or LOW BUF
+//	* / MOD SHL SHR          multiplication, division, remainder and shifts
+//	+ -                      addition and subtraction, and unary plus and minus
+//	EQ NE LT LE GT GE        comparisons, worth 0FFFFH when they hold and 0 otherwise
+//	NOT                      bitwise complement
+//	AND                      bitwise and
+//	OR XOR                   bitwise or and exclusive or
+//
+// Operands are numbers, characters like 'A', or two of them for a 16-bit value, symbols, the location counter $,
+// and expressions in parentheses. Numbers are decimal, with an optional D suffix, hexadecimal with an H suffix, like
+// 0FFH, or a $ prefix, like $FF, octal with a Q or O suffix, and binary with a B suffix. Comparisons and shifts
+// treat values as unsigned 16-bit numbers.
+//
+// The labels of the code and data segments, and the external symbols, are relocatable. Numbers can be added to and
+// subtracted from them, and they can be subtracted from and compared with those relative to the same segment or
+// symbol, giving absolute values. The rest of the operators only take absolute values.
+//
+// Symbols not defined yet evaluate to the value they got in the previous pass, or to 0 in the first one, as they
+// may be defined later in the source.
+func (a *assembler) expr(expr string) (value, error) {
+	p := &parser{a: a, s: expr}
+	v, err := p.or()
+	if err != nil {
+		return value{}, err
+	}
+	if p.skipSpaces(); p.pos < len(p.s) {
+		return value{}, fmt.Errorf("unexpected %q in expression %q", p.s[p.pos:], expr)
+	}
+	return v, nil
+}
+
+// parser evaluates an expression while reading it
+type parser struct {
+	a   *assembler
+	s   string
+	pos int
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// operator reads the next operator if it's one of the given ones, and returns it, in upper case
+func (p *parser) operator(ops ...string) (string, bool) {
+	p.skipSpaces()
+	rest := p.s[p.pos:]
+	for _, op := range ops {
+		if len(rest) < len(op) || !strings.EqualFold(rest[:len(op)], op) {
+			continue
+		}
+		// word operators must be whole words, not the start of a name
+		if isNameChar(op[0]) && len(rest) > len(op) && isNameChar(rest[len(op)]) {
+			continue
+		}
+		p.pos += len(op)
+		return op, true
+	}
+	return "", false
+}
+
+// binary evaluates a sequence of operands separated by the given operators, all with the same precedence, from
+// left to right
+func (p *parser) binary(operand func() (value, error), apply func(op string, x, y value) (value, error), ops ...string) (value, error) {
+	v, err := operand()
+	for err == nil {
+		op, ok := p.operator(ops...)
+		if !ok {
+			break
+		}
+		var w value
+		if w, err = operand(); err == nil {
+			v, err = apply(op, v, w)
+		}
+	}
+	return v, err
+}
+
+// arithmetic returns the binary operator applying f to absolute values
+func arithmetic(f func(op string, x, y int) (int, error)) func(op string, x, y value) (value, error) {
+	return func(op string, x, y value) (value, error) {
+		if x.rel != obj.Abs || y.rel != obj.Abs {
+			return value{}, fmt.Errorf("%s of a relocatable value", op)
+		}
+		n, err := f(op, x.n, y.n)
+		return absolute(n), err
+	}
+}
+
+func (p *parser) or() (value, error) {
+	return p.binary(p.and, arithmetic(func(op string, x, y int) (int, error) {
+		if op == "OR" {
+			return x | y, nil
+		}
+		return x ^ y, nil
+	}), "OR", "XOR")
+}
+
+func (p *parser) and() (value, error) {
+	return p.binary(p.not, arithmetic(func(_ string, x, y int) (int, error) { return x & y, nil }), "AND")
+}
+
+func (p *parser) not() (value, error) {
+	if _, ok := p.operator("NOT"); ok {
+		v, err := p.not()
+		if err == nil && v.rel != obj.Abs {
+			err = errors.New("NOT of a relocatable value")
+		}
+		return absolute(^v.n), err
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (value, error) {
+	return p.binary(p.sum, func(op string, x, y value) (value, error) {
+		if !x.sameBase(y) {
+			return value{}, fmt.Errorf("%s of values relative to different segments or symbols", op)
+		}
+		ux, uy := uint16(x.n), uint16(y.n)
+		holds := map[string]bool{
+			"EQ": ux == uy, "NE": ux != uy, "LT": ux < uy, "LE": ux <= uy, "GT": ux > uy, "GE": ux >= uy,
+		}[op]
+		if holds {
+			return absolute(true16), nil
+		}
+		return absolute(0), nil
+	}, "EQ", "NE", "LT", "LE", "GT", "GE")
+}
+
+func (p *parser) sum() (value, error) {
+	return p.binary(p.product, func(op string, x, y value) (value, error) {
+		switch {
+		case op == "+" && x.rel == obj.Abs:
+			return value{n: x.n + y.n, rel: y.rel, ext: y.ext}, nil
+		case y.rel == obj.Abs && op == "+":
+			return value{n: x.n + y.n, rel: x.rel, ext: x.ext}, nil
+		case y.rel == obj.Abs:
+			return value{n: x.n - y.n, rel: x.rel, ext: x.ext}, nil
+		case op == "-" && x.sameBase(y):
+			return absolute(x.n - y.n), nil
+		}
+		return value{}, fmt.Errorf("%s of values relative to different segments or symbols", op)
+	}, "+", "-")
+}
+
+func (p *parser) product() (value, error) {
+	return p.binary(p.unary, arithmetic(func(op string, x, y int) (int, error) {
+		switch op {
+		case "*":
+			return x * y, nil
+		case "SHL":
+			return int(uint16(x) << uint(y&0x1F)), nil
+		case "SHR":
+			return int(uint16(x) >> uint(y&0x1F)), nil
+		}
+		if y == 0 {
+			return 0, errors.New("division by zero")
+		}
+		if op == "/" {
+			return x / y, nil
+		}
+		return x % y, nil
+	}), "*", "/", "MOD", "SHL", "SHR")
+}
+
+// unary evaluates an operand, preceded by any number of unary operators
+func (p *parser) unary() (value, error) {
+	op, ok := p.operator("+", "-", "HIGH", "LOW")
+	if !ok {
+		return p.operand()
+	}
+	v, err := p.unary()
+	if err != nil || op == "+" {
+		return v, err
+	}
+	if v.rel != obj.Abs {
+		return value{}, fmt.Errorf("%s of a relocatable value", op)
+	}
+	switch op {
+	case "-":
+		v.n = -v.n
+	case "HIGH":
+		v.n = int(uint16(v.n) >> 8)
+	case "LOW":
+		v.n = int(uint16(v.n) & 0xFF)
+	}
+	return v, nil
+}
+
+// operand evaluates a number, character constant, symbol, $, or parenthesized expression
+func (p *parser) operand() (value, error) {
+	p.skipSpaces()
+	if p.pos == len(p.s) {
+		return value{}, fmt.Errorf("missing operand in expression %q", p.s)
+	}
+
+	switch c := p.s[p.pos]; {
+	case c == '(':
+		p.pos++
+		v, err := p.or()
+		if err != nil {
+			return value{}, err
+		}
+		if _, ok := p.operator(")"); !ok {
+			return value{}, fmt.Errorf("missing ) in expression %q", p.s)
+		}
+		return v, nil
+	case c == '\'' || c == '"':
+		s, n, err := unquote(p.s[p.pos:])
+		if err != nil {
+			return value{}, err
+		}
+		if len(s) == 0 || len(s) > 2 {
+			return value{}, fmt.Errorf("character constant %s must hold 1 or 2 characters", p.s[p.pos:p.pos+n])
+		}
+		p.pos += n
+		v := 0
+		for i := 0; i < len(s); i++ {
+			v = v<<8 | int(s[i])
+		}
+		return absolute(v), nil
+	}
+
+	word := p.word()
+	switch {
+	case word == "":
+		return value{}, fmt.Errorf("unexpected %q in expression %q", p.s[p.pos:], p.s)
+	case word == "$":
+		return value{n: int(p.a.pc), rel: p.a.seg}, nil
+	case word[0] == '$' || word[0] >= '0' && word[0] <= '9':
+		n, err := parseNumber(word)
+		return absolute(n), err
+	case operators[strings.ToUpper(word)]:
+		return value{}, fmt.Errorf("missing operand before %s in expression %q", word, p.s)
+	}
+	return p.a.value(word)
+}
+
+// word reads a number or a name
+func (p *parser) word() string {
+	start := p.pos
+	for p.pos < len(p.s) && isNameChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// isNameChar tells whether the character can be part of a name or a number
+func isNameChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("_?@.$", c) >= 0
+}
+
+// parseNumber parses a number in any of the notations understood by eval
+func parseNumber(s string) (int, error) {
+	digits := strings.ToUpper(s)
+	base := 10
+	switch {
+	case digits[0] == '$':
+		base, digits = 16, digits[1:]
+	case strings.HasSuffix(digits, "H"):
+		base, digits = 16, digits[:len(digits)-1]
+	case strings.HasSuffix(digits, "Q") || strings.HasSuffix(digits, "O"):
+		base, digits = 8, digits[:len(digits)-1]
+	case strings.HasSuffix(digits, "B"):
+		base, digits = 2, digits[:len(digits)-1]
+	case strings.HasSuffix(digits, "D"):
+		digits = digits[:len(digits)-1]
+	}
+	v, err := strconv.ParseUint(digits, base, 32)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, fmt.Errorf("number %s out of range", s)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %s", s)
+	}
+	return int(v), nil
+}
+
+// unquote reads the string literal at the start of s, quoted with ' or ", where the quote is escaped by doubling it,
+// and returns its contents and the number of characters read
+func unquote(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != quote {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == quote {
+			b.WriteByte(quote)
+			i++
+			continue
+		}
+		return b.String(), i + 1, nil
+	}
+	return "", 0, fmt.Errorf("unterminated string %s", s)
+}
@@ -0,0 +1,197 @@
+package asm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/miguelff/8080/obj"
+)
+
+// bytesPerRow is the number of bytes shown in each row of the listing
+const bytesPerRow = 4
+
+// listed is a line of the source as assembled in the final pass, shown in the listing
+type listed struct {
+	line
+	// nested tells whether the line comes from an included file, or the expansion of a macro or REPT
+	nested bool
+	// addr is the address of the bytes assembled, or of the label defined, when located, or their offset in the
+	// segment seg
+	addr    uint16
+	seg     obj.Kind
+	located bool
+	bytes   []byte
+	// equ is the value of the symbol defined with EQU or SET, when defined
+	equ     value
+	defined bool
+}
+
+// relMarks mark the addresses and values of the listing relative to the code and data segments, and to external
+// symbols
+var relMarks = map[obj.Kind]string{obj.Abs: " ", obj.Code: "'", obj.Data: "\"", obj.External: "*"}
+
+// list adds the given lines to the listing, in the final pass
+func (a *assembler) list(lines ...line) {
+	if !a.final {
+		return
+	}
+	for _, l := range lines {
+		a.listing = append(a.listing, &listed{line: l, nested: len(a.sources) > 1})
+	}
+}
+
+// listed returns the line of the listing being assembled, or nil before the final pass
+func (a *assembler) listed() *listed {
+	if !a.final || len(a.listing) == 0 {
+		return nil
+	}
+	return a.listing[len(a.listing)-1]
+}
+
+// locate shows the given address in the listing of the current line, unless it already has one
+func (a *assembler) locate(addr uint16) {
+	if l := a.listed(); l != nil && !l.located {
+		l.addr, l.seg, l.located = addr, a.seg, true
+	}
+}
+
+// WriteListing writes the listing of the program: the address, bytes, line number and source code of each line
+// assembled, followed by the symbol table after an empty line. The lines of included files and expansions are
+// marked with a +, and the symbols defined with EQU and SET show their values in place of the bytes. The offsets in
+// the code and data segments are followed by ' and ", and the values relative to external symbols by *, in the
+// lines and the symbol table alike:
+//
+//	0100  3E 41          12  LOOP:   MVI A,'A'
+//	      = 0003         13+ COUNT   EQU 3
+//	0004' CD 00 00       14          CALL PRINT
+//
+// Package symbols reads listings back.
+func (p *Program) WriteListing(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, l := range p.listing {
+		addr, bytes := "     ", ""
+		if l.located {
+			addr = fmt.Sprintf("%04X%s", l.addr, relMarks[l.seg])
+		}
+		if l.defined {
+			bytes = fmt.Sprintf("= %04X%s", uint16(l.equ.n), strings.TrimSpace(relMarks[l.equ.rel]))
+		}
+		rows := hexRows(l.bytes)
+		if len(rows) > 0 {
+			bytes = rows[0]
+		}
+		mark := " "
+		if l.nested {
+			mark = "+"
+		}
+		row := fmt.Sprintf("%s %-11s %5d%s %s", addr, bytes, l.number, mark, l.text)
+		fmt.Fprintln(bw, strings.TrimRight(row, " \t"))
+		for i := 1; i < len(rows); i++ {
+			fmt.Fprintf(bw, "%04X%s %s\n", l.addr+uint16(i*bytesPerRow), relMarks[l.seg], rows[i])
+		}
+	}
+	fmt.Fprintln(bw)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return p.writeSymbols(w, true)
+}
+
+// WriteLineMap writes the line map of the program, telling the source line each instruction and data line was
+// assembled from, in the format read by package dap:
+//
+//	; address file:line
+//	0100 hello.asm:12
+//	0102 hello.asm:13
+//
+// The lines of macros are mapped to the lines of their bodies. Files are written relative to dir, the directory of
+// the line map, when given. Only the code at absolute addresses is mapped, as the rest is placed by the linker.
+func (p *Program) WriteLineMap(w io.Writer, dir string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "; address file:line")
+	for _, l := range p.listing {
+		if !l.located || len(l.bytes) == 0 || l.seg != obj.Abs {
+			continue
+		}
+		file := l.file
+		if dir != "" {
+			file = relative(dir, file)
+		}
+		fmt.Fprintf(bw, "%04X %s:%d\n", l.addr, filepath.ToSlash(file), l.number)
+	}
+	return bw.Flush()
+}
+
+// relative returns the path relative to the given directory, or the path itself when it can't be made relative
+func relative(dir, path string) string {
+	dir, derr := filepath.Abs(dir)
+	abs, perr := filepath.Abs(path)
+	if derr != nil || perr != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(dir, abs); err == nil {
+		return rel
+	}
+	return path
+}
+
+// hexRows returns the bytes in hexadecimal, in rows of bytesPerRow
+func hexRows(bytes []byte) []string {
+	var rows []string
+	for i := 0; i < len(bytes); i += bytesPerRow {
+		end := i + bytesPerRow
+		if end > len(bytes) {
+			end = len(bytes)
+		}
+		row := make([]string, 0, bytesPerRow)
+		for _, b := range bytes[i:end] {
+			row = append(row, fmt.Sprintf("%02X", b))
+		}
+		rows = append(rows, strings.Join(row, " "))
+	}
+	return rows
+}
+
+// symbolsPerRow is the number of symbols in each line of symbol files
+const symbolsPerRow = 4
+
+// WriteSymbols writes the symbols of the program in the format of CP/M .SYM files, sorted by address, which package
+// symbols reads back:
+//
+//	0100 START           0103 LOOP            0106 DONE
+//
+// Only the symbols with absolute values are written, as the addresses of relocatable ones are only known once linked,
+// and the map written by the linker holds them.
+func (p *Program) WriteSymbols(w io.Writer) error {
+	return p.writeSymbols(w, false)
+}
+
+// writeSymbols writes the symbols of the program. The relocatable ones are followed by their marks when marked, and
+// left out otherwise.
+func (p *Program) writeSymbols(w io.Writer, marked bool) error {
+	var names []string
+	for _, name := range p.Symbols.Names() {
+		if _, ok := p.rels[name]; marked || !ok {
+			names = append(names, name)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for i, name := range names {
+		addr, _ := p.Symbols.Addr(name)
+		mark := ""
+		if marked {
+			mark = strings.TrimSpace(relMarks[p.rels[name]])
+		}
+		switch {
+		case i%symbolsPerRow == symbolsPerRow-1 || i == len(names)-1:
+			fmt.Fprintf(bw, "%04X%s %s\n", addr, mark, name)
+		default:
+			fmt.Fprintf(bw, "%04X%s %-*s ", addr, mark, 15-len(mark), name)
+		}
+	}
+	return bw.Flush()
+}
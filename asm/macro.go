@@ -0,0 +1,307 @@
+package asm
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxNesting is the maximum number of sources open at once, to stop runaway recursive macros
+const maxNesting = 64
+
+// macro is a macro defined with MACRO
+type macro struct {
+	name   string
+	params []string
+	body   []line
+	// pass is the pass the macro was defined in
+	pass int
+}
+
+// cond is a conditional block entered with IF
+type cond struct {
+	line *line
+	// outer tells whether the lines around the block are assembled
+	outer bool
+	// taken tells whether the lines of the current branch are assembled
+	taken   bool
+	sawElse bool
+}
+
+// skipping tells whether the current line is in a branch of a conditional that isn't assembled
+func (a *assembler) skipping() bool {
+	return len(a.conds) > 0 && !a.conds[len(a.conds)-1].taken
+}
+
+// conditional handles IF, ELSE and ENDIF
+func (a *assembler) conditional(st statement) error {
+	if st.label != "" {
+		return fmt.Errorf("%s can't have a label", st.op)
+	}
+	src := a.sources[len(a.sources)-1]
+
+	switch st.op {
+	case "IF":
+		c := &cond{line: a.line, outer: !a.skipping()}
+		a.conds = append(a.conds, c)
+		if !c.outer {
+			return nil
+		}
+		if len(st.operands) != 1 {
+			return fmt.Errorf("IF takes 1 operand, got %d", len(st.operands))
+		}
+		v, err := a.eval(st.operands[0])
+		if err != nil {
+			// the code assembled depends on the condition, so it can't refer to symbols defined later
+			return earlyError{err}
+		}
+		c.taken = v != 0
+	case "ELSE":
+		if len(a.conds) <= src.conds {
+			return errors.New("ELSE without IF")
+		}
+		c := a.conds[len(a.conds)-1]
+		if c.sawElse {
+			return errors.New("ELSE after ELSE")
+		}
+		c.sawElse = true
+		c.taken = c.outer && !c.taken
+	case "ENDIF":
+		if len(a.conds) <= src.conds {
+			return errors.New("ENDIF without IF")
+		}
+		a.conds = a.conds[:len(a.conds)-1]
+	}
+	return nil
+}
+
+// closeSource ends the source at the top of the stack, reporting its conditional blocks left open
+func (a *assembler) closeSource(src *source) {
+	for _, c := range a.conds[src.conds:] {
+		a.failAt(c.line, earlyError{errors.New("IF without ENDIF")})
+	}
+	a.conds = a.conds[:src.conds]
+	a.sources = a.sources[:len(a.sources)-1]
+}
+
+// push starts assembling the given lines, before the rest of the current source
+func (a *assembler) push(src *source) error {
+	if len(a.sources) >= maxNesting {
+		return earlyError{fmt.Errorf("more than %d nested macros and includes", maxNesting)}
+	}
+	src.conds = len(a.conds)
+	a.sources = append(a.sources, src)
+	return nil
+}
+
+// body reads the lines following MACRO or REPT up to the matching ENDM, which must be found in the same source.
+// Nested MACRO and REPT blocks are part of the body.
+func (a *assembler) body(st statement) ([]line, error) {
+	src := a.sources[len(a.sources)-1]
+	start := src.next
+	depth := 1
+	for ; src.next < len(src.lines); src.next++ {
+		inner, err := parse(src.lines[src.next].text)
+		if err != nil {
+			continue
+		}
+		switch inner.op {
+		case "MACRO", "REPT":
+			depth++
+		case "ENDM":
+			depth--
+		}
+		if depth == 0 {
+			src.next++
+			a.list(src.lines[start:src.next]...)
+			return src.lines[start : src.next-1], nil
+		}
+	}
+	return nil, earlyError{fmt.Errorf("%s without ENDM", st.op)}
+}
+
+// defineMacro defines the macro whose body follows
+func (a *assembler) defineMacro(st statement) error {
+	body, err := a.body(st)
+	if err != nil {
+		return err
+	}
+	if st.label == "" {
+		return earlyError{errors.New("MACRO needs a name")}
+	}
+	name := strings.ToUpper(st.label)
+	if isReserved(name) {
+		return earlyError{fmt.Errorf("%s is a reserved word", st.label)}
+	}
+	if m, ok := a.macros[name]; ok && m.pass == a.pass {
+		return earlyError{fmt.Errorf("macro %s redefined", st.label)}
+	}
+	for _, param := range st.operands {
+		if !isName(param) {
+			return earlyError{fmt.Errorf("invalid macro parameter %q", param)}
+		}
+	}
+	a.macros[name] = &macro{name: name, params: st.operands, body: body, pass: a.pass}
+	return nil
+}
+
+// expand assembles the body of the macro, replacing its parameters with the given arguments, and the local labels
+// declared with LOCAL with names unique to the expansion, like ??0001
+func (a *assembler) expand(m *macro, args []string) error {
+	if len(args) > len(m.params) {
+		return fmt.Errorf("macro %s takes %d arguments, got %d", m.name, len(m.params), len(args))
+	}
+	replace := make(map[string]string)
+	for i, param := range m.params {
+		replace[param] = ""
+		if i < len(args) {
+			replace[param] = args[i]
+		}
+	}
+
+	var body []line
+	for _, l := range m.body {
+		st, err := parse(l.text)
+		if err != nil || st.op != "LOCAL" {
+			body = append(body, l)
+			continue
+		}
+		for _, name := range st.operands {
+			a.expansions++
+			replace[name] = fmt.Sprintf("??%04d", a.expansions)
+		}
+	}
+
+	x := &expansion{macro: m.name, call: *a.line}
+	lines := make([]line, len(body))
+	for i, l := range body {
+		lines[i] = line{file: l.file, number: l.number, text: substitute(l.text, replace), expansion: x}
+	}
+	return a.push(&source{lines: lines})
+}
+
+// substitute replaces the names in the text found in replace, out of strings and comments. The & operator
+// concatenates a name with the text around it, as in LABEL&N.
+func substitute(text string, replace map[string]string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ';':
+			b.WriteString(text[i:])
+			return b.String()
+		case c == '\'' || c == '"':
+			_, n, err := unquote(text[i:])
+			if err != nil {
+				b.WriteString(text[i:])
+				return b.String()
+			}
+			b.WriteString(text[i : i+n])
+			i += n
+		case c == '&':
+			i++
+		case isNameChar(c):
+			start := i
+			for i < len(text) && isNameChar(text[i]) {
+				i++
+			}
+			word := text[start:i]
+			if r, ok := replace[word]; ok {
+				word = r
+			}
+			b.WriteString(word)
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// repeat assembles the block following REPT the given number of times
+func (a *assembler) repeat(st statement) error {
+	body, err := a.body(st)
+	if err != nil {
+		return err
+	}
+	if len(st.operands) != 1 {
+		return fmt.Errorf("REPT takes 1 operand, got %d", len(st.operands))
+	}
+	n, err := a.eval(st.operands[0])
+	if err != nil {
+		return earlyError{err}
+	}
+	if n < 0 {
+		return fmt.Errorf("negative REPT count %d", n)
+	}
+
+	var lines []line
+	for i := 0; i < n; i++ {
+		lines = append(lines, body...)
+	}
+	return a.push(&source{lines: lines})
+}
+
+// includePath returns the path of the file included by the statement, relative to the file including it
+func (a *assembler) includePath(st statement) (string, error) {
+	if len(st.operands) != 1 {
+		return "", fmt.Errorf("%s takes 1 operand, got %d", st.op, len(st.operands))
+	}
+	path := st.operands[0]
+	if path[0] == '\'' || path[0] == '"' {
+		var err error
+		if path, _, err = unquote(path); err != nil {
+			return "", err
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(a.line.file), path)
+	}
+	return path, nil
+}
+
+// include assembles the source file included by the statement
+func (a *assembler) include(st statement) error {
+	path, err := a.includePath(st)
+	if err != nil {
+		return earlyError{err}
+	}
+	for _, src := range a.sources {
+		if src.path == path {
+			return earlyError{fmt.Errorf("%s includes itself", path)}
+		}
+	}
+
+	lines, ok := a.files[path]
+	if !ok {
+		f, err := os.Open(path)
+		if err != nil {
+			return earlyError{err}
+		}
+		defer f.Close()
+		if lines, err = readLines(path, f); err != nil {
+			return earlyError{err}
+		}
+		a.files[path] = lines
+	}
+	return a.push(&source{lines: lines, path: path})
+}
+
+// includeBinary assembles the contents of the binary file included by the statement as data
+func (a *assembler) includeBinary(st statement) error {
+	path, err := a.includePath(st)
+	if err != nil {
+		return earlyError{err}
+	}
+	bin, ok := a.binaries[path]
+	if !ok {
+		if bin, err = ioutil.ReadFile(path); err != nil {
+			return earlyError{err}
+		}
+		a.binaries[path] = bin
+	}
+	return a.emit(bin...)
+}
@@ -0,0 +1,137 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// statement is a parsed line of source code
+type statement struct {
+	label string
+	// op is the mnemonic or directive, in upper case
+	op       string
+	operands []string
+}
+
+// parse splits a line of source code into its label, operation and operands, leaving out its comment. Labels end
+// with a colon, which can be left out when they start at the first column, unless they're a mnemonic or directive,
+// and when they name the symbol defined by EQU or SET, or the macro defined by MACRO.
+func parse(text string) (statement, error) {
+	text, err := stripComment(text)
+	if err != nil {
+		return statement{}, err
+	}
+
+	var st statement
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return st, nil
+	}
+
+	first := fields[0]
+	switch {
+	case strings.HasSuffix(first, ":"):
+		st.label, text = strings.TrimSuffix(first, ":"), text[strings.Index(text, ":")+1:]
+	case text[0] != ' ' && text[0] != '\t' && !isOp(first),
+		len(fields) > 1 && named[strings.ToUpper(fields[1])]:
+		st.label, text = first, text[strings.Index(text, first)+len(first):]
+	}
+	if st.label != "" && !isName(st.label) {
+		return st, fmt.Errorf("invalid label %q", st.label)
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return st, nil
+	}
+	end := strings.IndexAny(text, " \t")
+	if end < 0 {
+		end = len(text)
+	}
+	st.op = strings.ToUpper(text[:end])
+	st.operands, err = splitOperands(text[end:])
+	return st, err
+}
+
+// named are the directives taking the name they define as label
+var named = map[string]bool{"EQU": true, "SET": true, "MACRO": true}
+
+// isOp tells whether s is a mnemonic or directive
+func isOp(s string) bool {
+	s = strings.ToUpper(s)
+	_, ok := forms[s]
+	return ok || directives[s]
+}
+
+// isReserved tells whether s is a mnemonic, directive or operator, which can't be used as a name
+func isReserved(s string) bool {
+	return isOp(s) || operators[strings.ToUpper(s)]
+}
+
+// isName tells whether s is a valid symbol name: letters, digits, and _?@.$, not starting with a digit or $
+func isName(s string) bool {
+	if s == "" || s[0] == '$' || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isNameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// stripComment removes the comment starting with ; from the line, unless it's found in a string
+func stripComment(text string) (string, error) {
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case ';':
+			return text[:i], nil
+		case '\'', '"':
+			_, n, err := unquote(text[i:])
+			if err != nil {
+				return "", err
+			}
+			i += n - 1
+		}
+	}
+	return text, nil
+}
+
+// splitOperands splits the operands of an instruction or directive, separated by commas out of strings and
+// parentheses
+func splitOperands(text string) ([]string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	var operands []string
+	depth, start := 0, 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '\'', '"':
+			_, n, err := unquote(text[i:])
+			if err != nil {
+				return nil, err
+			}
+			i += n - 1
+		case ',':
+			if depth == 0 {
+				operands = append(operands, strings.TrimSpace(text[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	operands = append(operands, strings.TrimSpace(text[start:]))
+	for _, operand := range operands {
+		if operand == "" {
+			return nil, fmt.Errorf("missing operand in %q", text)
+		}
+	}
+	return operands, nil
+}
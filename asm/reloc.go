@@ -0,0 +1,95 @@
+package asm
+
+import (
+	"fmt"
+
+	"github.com/miguelff/8080/obj"
+)
+
+// segment handles ASEG, CSEG and DSEG, switching to the absolute, code or data segment. Each one keeps its own
+// location counter.
+func (a *assembler) segment(st statement) error {
+	if st.label != "" {
+		return fmt.Errorf("%s can't have a label", st.op)
+	}
+	if len(st.operands) != 0 {
+		return fmt.Errorf("%s takes no operands", st.op)
+	}
+	seg := map[string]obj.Kind{"ASEG": obj.Abs, "CSEG": obj.Code, "DSEG": obj.Data}[st.op]
+	if seg != obj.Abs {
+		a.relocatable = true
+	}
+	a.pcs[a.seg] = a.pc
+	a.seg, a.pc = seg, a.pcs[seg]
+	return nil
+}
+
+// linkage handles PUBLIC, declaring the symbols defined in the source that other modules can use, and EXTRN,
+// declaring those used from other modules
+func (a *assembler) linkage(st statement) error {
+	if st.label != "" {
+		return fmt.Errorf("%s can't have a label", st.op)
+	}
+	if len(st.operands) == 0 {
+		return fmt.Errorf("%s needs a name", st.op)
+	}
+	a.relocatable = true
+	for _, name := range st.operands {
+		if !isName(name) {
+			return fmt.Errorf("invalid name %q", name)
+		}
+		if st.op == "EXTRN" {
+			if err := a.define(name, value{rel: obj.External, ext: name}, extern); err != nil {
+				return err
+			}
+			if a.final {
+				a.externals = append(a.externals, name)
+			}
+			continue
+		}
+
+		// symbols defined later in the source were defined in the previous passes
+		if !a.final {
+			continue
+		}
+		s, ok := a.symbols[name]
+		switch {
+		case !ok:
+			return fmt.Errorf("%w %s", errUndefined, name)
+		case s.kind == extern:
+			return fmt.Errorf("%s is external", name)
+		}
+		for _, public := range a.publics {
+			if public == name {
+				return fmt.Errorf("%s already public", name)
+			}
+		}
+		a.publics = append(a.publics, name)
+	}
+	return nil
+}
+
+// relocate records that the word assembled at the given offset of the current segment holds the given value, which
+// the linker relocates when it isn't absolute
+func (a *assembler) relocate(offset uint16, v value) {
+	if !a.final || v.rel == obj.Abs {
+		return
+	}
+	a.relocs = append(a.relocs, obj.Reloc{Kind: a.seg, Offset: offset, To: v.rel, Ext: v.ext})
+}
+
+// object returns the program assembled as a module, with the given bytes assembled at absolute addresses
+func (a *assembler) object(abs []Segment) *obj.Object {
+	o := &obj.Object{
+		Abs:       abs,
+		Code:      obj.Section{Size: uint16(a.images[obj.Code].size), Bytes: a.images[obj.Code].runs()},
+		Data:      obj.Section{Size: uint16(a.images[obj.Data].size), Bytes: a.images[obj.Data].runs()},
+		Externals: a.externals,
+		Relocs:    a.relocs,
+	}
+	for _, name := range a.publics {
+		s := a.symbols[name]
+		o.Publics = append(o.Publics, obj.Symbol{Name: name, Kind: s.rel, Value: s.value})
+	}
+	return o
+}
@@ -0,0 +1,111 @@
+// asm8080 assembles 8080 source code into a flat binary, an Intel HEX file or a relocatable object.
+//
+// Usage:
+//
+//	asm8080 [-o file] [-listing] [-sym] [-lines] source.asm
+//
+// The binary holds the bytes from the lowest address assembled to the highest one, and is written next to the
+// source with a .bin extension unless told otherwise. Its origin is printed. When the file written has a .hex
+// extension, the program is written in Intel HEX format instead, keeping the gaps between its segments. Errors are
+// reported as file:line: message. See package asm for the syntax of sources.
+//
+// Sources using CSEG, DSEG, PUBLIC or EXTRN are relocatable, and are written as objects with a .o80 extension, to
+// be linked with link8080. Any source is written as an object when the file written has that extension.
+//
+// With -listing, the listing of the program is written next to the binary with a .prn extension, showing the
+// address, bytes, line number and source code of each line, followed by the symbol table. With -sym, the symbols
+// with absolute addresses are written next to the binary in a CP/M .SYM file, as those of relocatable code are only
+// known once linked. Both are understood by the -sym flags of dasm and the emulator, and listings also show the
+// source code of the instructions traced. With -lines, the line map of the program, telling the source line each
+// address was assembled from, is written next to the binary with a .lines extension, for setting breakpoints on
+// source lines with the debug adapter of package dap.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/miguelff/8080/asm"
+	"github.com/miguelff/8080/encoding/ihex"
+	"github.com/miguelff/8080/obj"
+)
+
+func main() {
+	out := flag.String("o", "", "file written. Defaults to the source with a .bin extension, or .o80 if relocatable")
+	listing := flag.Bool("listing", false, "write the listing of the program, with a .prn extension")
+	sym := flag.Bool("sym", false, "write the symbols of the program, with a .sym extension")
+	lines := flag.Bool("lines", false, "write the line map of the program, with a .lines extension")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: asm8080 [-o file] [-listing] [-sym] [-lines] source.asm")
+		os.Exit(2)
+	}
+
+	source := flag.Arg(0)
+	p, err := asm.AssembleFile(source)
+	if err != nil {
+		fail(err)
+	}
+	if *out == "" {
+		ext := ".bin"
+		if p.Relocatable {
+			ext = ".o80"
+		}
+		*out = strings.TrimSuffix(source, filepath.Ext(source)) + ext
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(*out)); {
+	case ext == ".o80":
+		o := p.Object()
+		write(*out, func(w io.Writer) error { return obj.Write(w, o) })
+		fmt.Printf("%s: %d bytes of code, %d of data\n", *out, o.Code.Size, o.Data.Size)
+	case p.Relocatable:
+		fail(fmt.Errorf("%s is relocatable: write it as an object (.o80) and link it with link8080", source))
+	case ext == ".hex":
+		write(*out, func(w io.Writer) error { return ihex.Write(w, p.Segments) })
+		bin, origin := p.Binary()
+		fmt.Printf("%s: %d bytes at %04XH\n", *out, len(bin), origin)
+	default:
+		bin, origin := p.Binary()
+		if err := ioutil.WriteFile(*out, bin, 0644); err != nil {
+			fail(err)
+		}
+		fmt.Printf("%s: %d bytes at %04XH\n", *out, len(bin), origin)
+	}
+
+	base := strings.TrimSuffix(*out, filepath.Ext(*out))
+	if *listing {
+		write(base+".prn", p.WriteListing)
+	}
+	if *sym {
+		write(base+".sym", p.WriteSymbols)
+	}
+	if *lines {
+		write(base+".lines", func(w io.Writer) error { return p.WriteLineMap(w, filepath.Dir(base)) })
+	}
+}
+
+// write creates the file at the given path with the contents written by the given function
+func write(path string, contents func(w io.Writer) error) {
+	f, err := os.Create(path)
+	if err != nil {
+		fail(err)
+	}
+	if err := contents(f); err != nil {
+		f.Close()
+		fail(err)
+	}
+	if err := f.Close(); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
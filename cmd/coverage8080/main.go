@@ -0,0 +1,56 @@
+// coverage8080 prints which opcodes are decoded by the disassembler, executed by the emulator and tested.
+//
+// Usage:
+//
+//	coverage8080 [-tests file] [-missing]
+//
+// The opcodes are printed as a 16×16 matrix, with their high nibble in the rows and their low nibble in the
+// columns. Each cell holds D when the opcode is decoded by package dasm, E when it's executed by package emu, and T
+// when a case of the emulator tests executes it, or a dot in their place. Undefined opcodes are left blank. The tests
+// are read from emu/computer_test.go, relative to the root of the repository, unless told otherwise.
+//
+// With -missing, the instructions not executed or not tested yet are listed after the matrix. The opcodes the
+// instruction set of package isa, the disassembler and the emulator disagree on are always reported as errors, and
+// make coverage8080 exit with status 1. Those decoded but not emulated yet must be listed in coverage.Pending.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/miguelff/8080/coverage"
+)
+
+func main() {
+	tests := flag.String("tests", "emu/computer_test.go", "source of the emulator tests")
+	missing := flag.Bool("missing", false, "list the instructions not executed or not tested")
+	flag.Parse()
+	if flag.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "usage: coverage8080 [-tests file] [-missing]")
+		os.Exit(2)
+	}
+
+	r, err := coverage.New(*tests)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := r.Write(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *missing {
+		fmt.Println()
+		for _, m := range r.Missing() {
+			fmt.Println(m)
+		}
+	}
+	if disagreements := r.Disagreements(); len(disagreements) > 0 {
+		for _, d := range disagreements {
+			fmt.Fprintln(os.Stderr, d)
+		}
+		os.Exit(1)
+	}
+}
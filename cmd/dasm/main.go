@@ -0,0 +1,239 @@
+// dasm disassembles the 8080 machine code read from its standard input.
+//
+// Usage:
+//
+//	dasm [-sym file] [-org addr] [-offset n] [-flow [-entry addr,...]] [-listing | -reassemble]
+//	     [-undefined data|alias|fail] [-syntax intel|zilog] < program
+//	dasm cfg [-func addr] [-sym file] [-org addr] [-entry addr,...] [-undefined data|alias|fail]
+//	     [-syntax intel|zilog] < program
+//	dasm calls [-dot] [-sym file] [-org addr] [-entry addr,...] [-undefined data|alias|fail] < program
+//	dasm xref [-inline] [-sym file] [-org addr] [-entry addr,...] [-undefined data|alias|fail]
+//	     [-syntax intel|zilog] < program
+//
+// With -sym, the addresses defined in the symbol file are shown by name. See package symbols for the formats
+// understood. With -flow, only the code reachable from the reset and RST vectors, and from the given entry points,
+// is disassembled, and the rest is shown as data. The entries of the jump tables dispatched through with PCHL are
+// shown with DW, and followed as entry points too. With -listing, the address and bytes of each instruction are shown
+// before it. With -reassemble, the output is source code that assembles back to the same program, with labels for
+// the targets of jumps and calls. -undefined tells what to do with the opcodes without a documented instruction:
+// showing them as data, the default, decoding them as the instructions they behave like, or failing. -syntax zilog
+// writes the instructions with Z80 mnemonics. Addresses and offsets are hexadecimal.
+//
+// The cfg command writes the control flow graph of the code reachable from the entry points in the DOT language of
+// Graphviz, split into basic blocks. With -func, only the blocks of the function starting at the given address are
+// written.
+//
+// The calls command writes the call graph of the program: its subroutines, found at the targets of calls and
+// restarts and at the entry points, with their extent, exits, callers and callees. Routines not called directly,
+// like interrupt handlers or the targets of jump tables, are flagged. With -dot, the graph is written in the DOT
+// language instead.
+//
+// The xref command writes the cross references of the program: the instructions reading, writing, jumping to or
+// loading into a register pair each address, and reading or writing each I/O port. With -inline, the code is
+// disassembled following its control flow instead, with the references to each address commented on its line, and
+// the address read, written or loaded by each instruction on the instruction's.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/miguelff/8080/dasm"
+	"github.com/miguelff/8080/symbols"
+)
+
+func main() {
+	command, args := "", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "":
+		disassemble(args)
+	case "cfg":
+		cfg(args)
+	case "calls":
+		calls(args)
+	case "xref":
+		xref(args)
+	default:
+		fail(fmt.Errorf("unknown command %q, want cfg, calls or xref", command))
+	}
+}
+
+func disassemble(args []string) {
+	fs := flag.NewFlagSet("dasm", flag.ExitOnError)
+	common := commonFlags(fs)
+	flow := fs.Bool("flow", false, "follow the control flow from the entry points, and show unreached bytes as data")
+	offset := fs.String("offset", "0", "number of bytes of the program skipped before disassembling")
+	listing := fs.Bool("listing", false, "show the address and bytes of each instruction")
+	reassemble := fs.Bool("reassemble", false, "write source code that assembles back to the same program. Implies -flow")
+	fs.Parse(args)
+
+	opts := common.options()
+	skip, err := symbols.ParseAddress(*offset)
+	if err != nil {
+		fail(err)
+	}
+	opts.Flow = *flow
+	opts.Listing = *listing
+	opts.Reassemble = *reassemble
+
+	if err := dasm.DisassembleWith(os.Stdin, os.Stdout, int(skip), opts); err != nil {
+		fail(err)
+	}
+}
+
+func cfg(args []string) {
+	fs := flag.NewFlagSet("dasm cfg", flag.ExitOnError)
+	common := commonFlags(fs)
+	function := fs.String("func", "", "address of the function whose blocks are written, instead of the whole program")
+	fs.Parse(args)
+
+	opts := common.options()
+	g, err := dasm.BuildCFG(readProgram(), opts)
+	if err != nil {
+		fail(err)
+	}
+	name := "program"
+	if *function != "" {
+		entry, err := symbols.ParseAddress(*function)
+		if err != nil {
+			fail(err)
+		}
+		if _, ok := g.Block(entry); !ok {
+			fail(fmt.Errorf("no code reached at %04X, add it with -entry", entry))
+		}
+		g = g.Function(entry)
+		name = fmt.Sprintf("%04X", entry)
+		if label, ok := opts.Symbols.Name(entry); ok {
+			name = label
+		}
+	}
+
+	if err := g.WriteDOT(os.Stdout, name, opts); err != nil {
+		fail(err)
+	}
+}
+
+func calls(args []string) {
+	fs := flag.NewFlagSet("dasm calls", flag.ExitOnError)
+	common := commonFlags(fs)
+	dot := fs.Bool("dot", false, "write the call graph in the DOT language")
+	fs.Parse(args)
+
+	opts := common.options()
+	g, err := dasm.BuildCFG(readProgram(), opts)
+	if err != nil {
+		fail(err)
+	}
+	cg := g.CallGraph()
+	if *dot {
+		err = cg.WriteDOT(os.Stdout, "calls", opts)
+	} else {
+		err = cg.WriteText(os.Stdout, opts)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func xref(args []string) {
+	fs := flag.NewFlagSet("dasm xref", flag.ExitOnError)
+	common := commonFlags(fs)
+	inline := fs.Bool("inline", false, "disassemble the code, commenting the references on the lines of the code")
+	fs.Parse(args)
+
+	opts := common.options()
+	bin := readProgram()
+	g, err := dasm.BuildCFG(bin, opts)
+	if err != nil {
+		fail(err)
+	}
+	x := g.Xrefs()
+	if *inline {
+		opts.Flow = true
+		opts.Comments = x.Comments()
+		err = dasm.DisassembleWith(bytes.NewReader(bin), os.Stdout, 0, opts)
+	} else {
+		err = x.WriteText(os.Stdout, opts)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+// sharedFlags are the flags shared by all the commands
+type sharedFlags struct {
+	sym, org, entries, undefined, syntax *string
+}
+
+func commonFlags(fs *flag.FlagSet) *sharedFlags {
+	return &sharedFlags{
+		sym:       fs.String("sym", "", "symbol file naming the addresses in the program"),
+		org:       fs.String("org", "0", "address the program is loaded at"),
+		entries:   fs.String("entry", "", "comma separated entry points whose control flow is followed, besides the reset and RST vectors"),
+		undefined: fs.String("undefined", "data", "what to do with undefined opcodes: data, alias or fail"),
+		syntax:    fs.String("syntax", "intel", "mnemonics the instructions are written with: intel or zilog"),
+	}
+}
+
+// options returns the disassembler options set by the common flags, and exits if they're invalid
+func (c *sharedFlags) options() dasm.Options {
+	var opts dasm.Options
+	var err error
+	if *c.sym != "" {
+		if opts.Symbols, err = symbols.Load(*c.sym); err != nil {
+			fail(err)
+		}
+	}
+	if opts.Origin, err = symbols.ParseAddress(*c.org); err != nil {
+		fail(err)
+	}
+	switch *c.undefined {
+	case "data":
+		opts.Undefined = dasm.UndefinedData
+	case "alias":
+		opts.Undefined = dasm.UndefinedAlias
+	case "fail":
+		opts.Undefined = dasm.UndefinedFail
+	default:
+		fail(fmt.Errorf("invalid -undefined policy %q, want data, alias or fail", *c.undefined))
+	}
+	switch *c.syntax {
+	case "intel":
+		opts.Syntax = dasm.Intel
+	case "zilog":
+		opts.Syntax = dasm.Zilog
+	default:
+		fail(fmt.Errorf("invalid -syntax %q, want intel or zilog", *c.syntax))
+	}
+	if *c.entries != "" {
+		for _, s := range strings.Split(*c.entries, ",") {
+			addr, err := symbols.ParseAddress(s)
+			if err != nil {
+				fail(err)
+			}
+			opts.Entries = append(opts.Entries, addr)
+		}
+	}
+	return opts
+}
+
+func readProgram() []byte {
+	bin, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fail(err)
+	}
+	return bin
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
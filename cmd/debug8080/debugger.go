@@ -0,0 +1,584 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/miguelff/8080/dasm"
+	"github.com/miguelff/8080/emu"
+	"github.com/miguelff/8080/symbols"
+)
+
+// maxHistory is the amount of commands kept in the history file
+const maxHistory = 500
+
+// backtraceDepth is the amount of stack words inspected when looking for return addresses
+const backtraceDepth = 32
+
+// debugger holds the state of a debugging session
+type debugger struct {
+	c   *emu.Computer
+	bp  *emu.Breakpoints
+	out io.Writer
+
+	history []string
+	// last is the command repeated when an empty line is entered
+	last string
+	// interrupted is set to 1 when the user asks to stop the running program
+	interrupted int32
+}
+
+// command is a debugger command. The first of its names is the canonical one, and the rest are aliases.
+type command struct {
+	names []string
+	args  string
+	help  string
+	run   func(d *debugger, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{[]string{"step", "s"}, "[n]", "execute n instructions (1 by default)", (*debugger).step},
+		{[]string{"next", "n"}, "", "execute the next instruction, running called subroutines until they return", (*debugger).next},
+		{[]string{"finish", "f"}, "", "run until the current subroutine returns", (*debugger).finish},
+		{[]string{"continue", "c"}, "", "run until a breakpoint or watchpoint is hit, or Ctrl-C is pressed", (*debugger).cont},
+		{[]string{"break", "b"}, "addr", "set a breakpoint", (*debugger).setBreak},
+		{[]string{"watch", "w"}, "addr", "set a watchpoint on writes to the address", (*debugger).setWatch},
+		{[]string{"delete", "del"}, "addr", "delete the breakpoint or watchpoint at the address", (*debugger).delete},
+		{[]string{"breaks", "info"}, "", "list breakpoints and watchpoints", (*debugger).listBreaks},
+		{[]string{"regs", "r"}, "", "show the registers", (*debugger).regs},
+		{[]string{"set", "set-reg"}, "reg value", "set a register: a b c d e h l f bc de hl sp pc", (*debugger).setReg},
+		{[]string{"mem", "x"}, "addr [len]", "dump len bytes of memory (64 by default)", (*debugger).mem},
+		{[]string{"edit", "e"}, "addr byte...", "write the bytes to memory", (*debugger).edit},
+		{[]string{"dis", "d"}, "[addr] [n]", "disassemble n instructions at the address (around the PC by default)", (*debugger).dis},
+		{[]string{"bt", "backtrace"}, "", "show the calls found in the stack", (*debugger).backtrace},
+		{[]string{"save"}, "file", "save the registers and memory to a file", (*debugger).save},
+		{[]string{"load"}, "file", "restore the registers and memory from a file", (*debugger).load},
+		{[]string{"history", "hist"}, "", "list the previous commands. Repeat them with !! or !n", (*debugger).listHistory},
+		{[]string{"help", "h", "?"}, "", "show this help", (*debugger).help},
+		{[]string{"quit", "q"}, "", "exit the debugger", nil},
+	}
+}
+
+func newDebugger(c *emu.Computer, out io.Writer) *debugger {
+	return &debugger{
+		c:   c,
+		bp:  emu.NewBreakpoints(),
+		out: out,
+	}
+}
+
+func (d *debugger) printf(format string, args ...interface{}) {
+	fmt.Fprintf(d.out, format, args...)
+}
+
+// interrupt stops the program if it's running
+func (d *debugger) interrupt() {
+	atomic.StoreInt32(&d.interrupted, 1)
+}
+
+// exec runs a command line, and returns whether the user asked to quit
+func (d *debugger) exec(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		line = d.last
+	} else {
+		expanded, err := d.expand(line)
+		if err != nil {
+			d.printf("%v\n", err)
+			return false
+		}
+		if expanded != line {
+			d.printf("%s\n", expanded)
+		}
+		line = expanded
+		d.history = append(d.history, line)
+	}
+	if line == "" {
+		return false
+	}
+	d.last = line
+
+	fields := strings.Fields(line)
+	cmd, ok := lookup(fields[0])
+	if !ok {
+		d.printf("unknown command %q, try \"help\"\n", fields[0])
+		return false
+	}
+	if cmd.run == nil {
+		return true
+	}
+	if err := cmd.run(d, fields[1:]); err != nil {
+		d.printf("%v\n", err)
+	}
+	return false
+}
+
+func lookup(name string) (command, bool) {
+	for _, cmd := range commands {
+		for _, n := range cmd.names {
+			if n == name {
+				return cmd, true
+			}
+		}
+	}
+	return command{}, false
+}
+
+// expand replaces the history references (!! and !n) in the line by the command they refer to
+func (d *debugger) expand(line string) (string, error) {
+	if !strings.HasPrefix(line, "!") {
+		return line, nil
+	}
+	if line == "!!" {
+		if len(d.history) == 0 {
+			return "", fmt.Errorf("history is empty")
+		}
+		return d.history[len(d.history)-1], nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 || n > len(d.history) {
+		return "", fmt.Errorf("no command %s in history", line)
+	}
+	return d.history[n-1], nil
+}
+
+func (d *debugger) loadHistory(file string) {
+	if file == "" {
+		return
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" {
+			d.history = append(d.history, line)
+		}
+	}
+}
+
+func (d *debugger) saveHistory(file string) error {
+	if file == "" {
+		return nil
+	}
+	history := d.history
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	return ioutil.WriteFile(file, []byte(strings.Join(history, "\n")+"\n"), 0600)
+}
+
+func (d *debugger) listHistory(_ []string) error {
+	for i, line := range d.history {
+		d.printf("%4d  %s\n", i+1, line)
+	}
+	return nil
+}
+
+func (d *debugger) help(_ []string) error {
+	for _, cmd := range commands {
+		usage := strings.Join(cmd.names, ", ")
+		if cmd.args != "" {
+			usage += " " + cmd.args
+		}
+		d.printf("  %-28s %s\n", usage, cmd.help)
+	}
+	d.printf("\nAddresses and values are hexadecimal. An empty line repeats the last command.\n")
+	return nil
+}
+
+// run runs the program with the given emu.Computer method (Run, Next or Finish) until it stops, and reports why it
+// stopped, and where. The program can also be stopped with Ctrl-C, or by the given predicate, which can be nil.
+func (d *debugger) run(how func(*emu.Computer, *emu.Breakpoints, func() bool) emu.Stop, until func() bool) {
+	atomic.StoreInt32(&d.interrupted, 0)
+	stop := func() bool {
+		return atomic.LoadInt32(&d.interrupted) != 0 || (until != nil && until())
+	}
+
+	st := how(d.c, d.bp, stop)
+	switch st.Reason {
+	case emu.StopBreakpoint:
+		d.printf("breakpoint at %04X\n", st.Addr)
+	case emu.StopWatchpoint:
+		d.printf("watchpoint: %04X written\n", st.Addr)
+	case emu.StopError:
+		d.printf("stopped: %v\n", st.Err)
+	case emu.StopRequested:
+		if atomic.LoadInt32(&d.interrupted) != 0 {
+			d.printf("interrupted\n")
+		}
+	}
+	d.where()
+}
+
+// where shows the instruction the program is stopped at
+func (d *debugger) where() {
+	d.printInstruction(d.c.PC)
+}
+
+func (d *debugger) step(args []string) error {
+	n := 1
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+			return fmt.Errorf("invalid number of instructions %q", args[0])
+		}
+	}
+
+	executed := 0
+	d.run((*emu.Computer).Run, func() bool {
+		executed++
+		return executed >= n
+	})
+	return nil
+}
+
+func (d *debugger) next(_ []string) error {
+	d.run((*emu.Computer).Next, nil)
+	return nil
+}
+
+func (d *debugger) finish(_ []string) error {
+	d.run((*emu.Computer).Finish, nil)
+	return nil
+}
+
+func (d *debugger) cont(_ []string) error {
+	d.run((*emu.Computer).Run, nil)
+	return nil
+}
+
+func (d *debugger) setBreak(args []string) error {
+	addr, err := d.addrArg(args)
+	if err != nil {
+		return err
+	}
+	d.bp.Break(addr)
+	d.printf("breakpoint at %04X\n", addr)
+	return nil
+}
+
+func (d *debugger) setWatch(args []string) error {
+	addr, err := d.addrArg(args)
+	if err != nil {
+		return err
+	}
+	d.bp.Watch(addr)
+	d.printf("watchpoint on %04X\n", addr)
+	return nil
+}
+
+func (d *debugger) delete(args []string) error {
+	addr, err := d.addrArg(args)
+	if err != nil {
+		return err
+	}
+	if !d.bp.IsBreak(addr) && !d.bp.IsWatched(addr) {
+		return fmt.Errorf("no breakpoint or watchpoint at %04X", addr)
+	}
+	d.bp.Clear(addr)
+	d.bp.Unwatch(addr)
+	return nil
+}
+
+func (d *debugger) listBreaks(_ []string) error {
+	for _, addr := range d.bp.Breaks() {
+		d.printf("break  %04X  %s\n", addr, d.instruction(addr))
+	}
+	for _, addr := range d.bp.Watches() {
+		d.printf("watch  %04X  %02X\n", addr, d.byteAt(addr))
+	}
+	return nil
+}
+
+func (d *debugger) regs(_ []string) error {
+	c := d.c
+	d.printf("A=%02X  BC=%04X  DE=%04X  HL=%04X  SP=%04X  PC=%04X  Flags: %s\n",
+		c.A, c.BC(), c.DE(), c.HL(), c.SP, c.PC, c.Flags)
+	return nil
+}
+
+func (d *debugger) setReg(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: set reg value")
+	}
+	v, err := symbols.ParseAddress(args[1])
+	if err != nil {
+		return err
+	}
+
+	c := d.c
+	regs8 := map[string]*byte{"a": &c.A, "b": &c.B, "c": &c.C, "d": &c.D, "e": &c.E, "h": &c.H, "l": &c.L}
+	pairs := map[string][2]*byte{"bc": {&c.B, &c.C}, "de": {&c.D, &c.E}, "hl": {&c.H, &c.L}}
+
+	reg := strings.ToLower(args[0])
+	switch {
+	case regs8[reg] != nil || reg == "f":
+		if v > 0xFF {
+			return fmt.Errorf("%s is an 8-bit register", args[0])
+		}
+		if reg == "f" {
+			c.Flags = emu.Flags(v)
+		} else {
+			*regs8[reg] = byte(v)
+		}
+	case pairs[reg][0] != nil:
+		*pairs[reg][0], *pairs[reg][1] = byte(v>>8), byte(v)
+	case reg == "sp":
+		c.SP = v
+	case reg == "pc":
+		c.PC = v
+	default:
+		return fmt.Errorf("unknown register %q", args[0])
+	}
+	return d.regs(nil)
+}
+
+func (d *debugger) mem(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: mem addr [len]")
+	}
+	addr, err := symbols.ParseAddress(args[0])
+	if err != nil {
+		return err
+	}
+	n := 64
+	if len(args) > 1 {
+		l, err := symbols.ParseAddress(args[1])
+		if err != nil {
+			return err
+		}
+		n = int(l)
+	}
+
+	for row := 0; row < n; row += 16 {
+		var hexs, ascii strings.Builder
+		for i := row; i < row+16 && i < n; i++ {
+			b := d.byteAt(addr + uint16(i))
+			hexs.WriteString(fmt.Sprintf("%02X ", b))
+			if b >= 0x20 && b < 0x7F {
+				ascii.WriteByte(b)
+			} else {
+				ascii.WriteByte('.')
+			}
+		}
+		d.printf("%04X: %-48s |%s|\n", addr+uint16(row), hexs.String(), ascii.String())
+	}
+	return nil
+}
+
+func (d *debugger) edit(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: edit addr byte...")
+	}
+	addr, err := symbols.ParseAddress(args[0])
+	if err != nil {
+		return err
+	}
+	bytes := make([]byte, len(args)-1)
+	for i, arg := range args[1:] {
+		v, err := symbols.ParseAddress(arg)
+		if err != nil || v > 0xFF {
+			return fmt.Errorf("invalid byte %q", arg)
+		}
+		bytes[i] = byte(v)
+	}
+	if int(addr)+len(bytes) > len(d.c.Mem) {
+		return fmt.Errorf("%04X is out of memory", int(addr)+len(bytes)-1)
+	}
+	copy(d.c.Mem[addr:], bytes)
+	return nil
+}
+
+func (d *debugger) dis(args []string) error {
+	addr := d.syncBefore(d.c.PC, 3)
+	n := 8
+	if len(args) > 0 {
+		var err error
+		if addr, err = symbols.ParseAddress(args[0]); err != nil {
+			return err
+		}
+	}
+	if len(args) > 1 {
+		var err error
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+			return fmt.Errorf("invalid number of instructions %q", args[1])
+		}
+	}
+
+	for i := 0; i < n && int(addr) < len(d.c.Mem); i++ {
+		size := d.printInstruction(addr)
+		addr += uint16(size)
+	}
+	return nil
+}
+
+// syncBefore looks for an address up to n instructions before addr, from which disassembling lands exactly on addr.
+// Code can't be reliably disassembled backwards, so addr itself is returned when no such address is found.
+func (d *debugger) syncBefore(addr uint16, n int) uint16 {
+	for back := 3 * n; back > 0; back-- {
+		if int(addr) < back {
+			continue
+		}
+		start := addr - uint16(back)
+		pos, count := start, 0
+		for pos < addr && count < n {
+			_, size := d.decode(pos)
+			pos += uint16(size)
+			count++
+		}
+		if pos == addr {
+			return start
+		}
+	}
+	return addr
+}
+
+func (d *debugger) backtrace(_ []string) error {
+	d.printf("#0  %04X  %s\n", d.c.PC, d.instruction(d.c.PC))
+	frame := 1
+	for i := 0; i < backtraceDepth; i++ {
+		sp := int(d.c.SP) + 2*i
+		if sp+1 >= len(d.c.Mem) {
+			break
+		}
+		word := uint16(d.c.Mem[sp+1])<<8 | uint16(d.c.Mem[sp])
+		if call, ok := d.caller(word); ok {
+			d.printf("#%d  %04X  %s  (SP+%d)\n", frame, call, d.instruction(call), 2*i)
+			frame++
+		}
+	}
+	return nil
+}
+
+// caller returns the address of the call instruction that pushed the given stack word, if it looks like it was pushed
+// by one. The emulator pushes the address of the call itself, while the 8080 pushes the address that follows it, so
+// both are considered.
+func (d *debugger) caller(word uint16) (uint16, bool) {
+	if int(word) >= len(d.c.Mem) {
+		return 0, false
+	}
+	if isCall(d.opcode(word)) || isRST(d.opcode(word)) {
+		return word, true
+	}
+	if word >= 3 && isCall(d.opcode(word-3)) {
+		return word - 3, true
+	}
+	if word >= 1 && isRST(d.opcode(word-1)) {
+		return word - 1, true
+	}
+	return 0, false
+}
+
+// state is the snapshot of the computer saved and loaded by the debugger
+type state struct {
+	CPU emu.CPU
+	Mem []byte
+}
+
+func (d *debugger) save(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: save file")
+	}
+	b, err := json.Marshal(state{CPU: d.c.CPU, Mem: d.c.Mem})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(args[0], b, 0644)
+}
+
+func (d *debugger) load(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: load file")
+	}
+	b, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	var s state
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("%s is not a saved state: %w", args[0], err)
+	}
+	if len(s.Mem) != len(d.c.Mem) {
+		return fmt.Errorf("%s has %d bytes of memory, want %d", args[0], len(s.Mem), len(d.c.Mem))
+	}
+	d.c.CPU = s.CPU
+	copy(d.c.Mem, s.Mem)
+	d.where()
+	return nil
+}
+
+func (d *debugger) addrArg(args []string) (uint16, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("an address is required")
+	}
+	return symbols.ParseAddress(args[0])
+}
+
+func (d *debugger) byteAt(addr uint16) byte {
+	if int(addr) >= len(d.c.Mem) {
+		return 0
+	}
+	return d.c.Mem[addr]
+}
+
+func (d *debugger) opcode(addr uint16) byte {
+	return d.byteAt(addr)
+}
+
+// decode disassembles the instruction at the given address, and returns it along with its size. Undefined opcodes
+// are shown as data.
+func (d *debugger) decode(addr uint16) (string, int) {
+	if int(addr) < len(d.c.Mem) {
+		if inst, err := dasm.Decode(d.c.Mem[addr:], addr); err == nil {
+			return inst.String(), inst.Size
+		}
+	}
+	return fmt.Sprintf("DB $%02X", d.byteAt(addr)), 1
+}
+
+func (d *debugger) instruction(addr uint16) string {
+	asm, _ := d.decode(addr)
+	return asm
+}
+
+// printInstruction shows the instruction at the given address, and returns its size
+func (d *debugger) printInstruction(addr uint16) int {
+	asm, size := d.decode(addr)
+	var raw strings.Builder
+	for i := 0; i < size; i++ {
+		raw.WriteString(fmt.Sprintf("%02X ", d.byteAt(addr+uint16(i))))
+	}
+
+	marker := "  "
+	if addr == d.c.PC {
+		marker = "=>"
+	} else if d.bp.IsBreak(addr) {
+		marker = " *"
+	}
+	d.printf("%s %04X  %-9s %s\n", marker, addr, raw.String(), asm)
+	return size
+}
+
+// isCall tells whether the opcode is a CALL or a conditional call
+func isCall(op byte) bool {
+	return op == 0xCD || op&0xC7 == 0xC4
+}
+
+// isRST tells whether the opcode is a RST
+func isRST(op byte) bool {
+	return op&0xC7 == 0xC7
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miguelff/8080/asm"
+	"github.com/miguelff/8080/emu"
+)
+
+// program is the source of the test program, shared with the tests of package dap:
+//
+//	0000 LXI SP,$0100
+//	0003 CALL $0010
+//	0006 INR B
+//	0007 JMP $0006
+//	0010 INR C
+//	0011 STA $0080
+//	0014 RET
+const program = "../../dap/testdata/prog.asm"
+
+func newTestDebugger(t *testing.T) (*debugger, *strings.Builder) {
+	t.Helper()
+	p, err := asm.AssembleFile(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin, _ := p.Binary()
+	out := new(strings.Builder)
+	return newDebugger(emu.LoadAt(bin, 0), out), out
+}
+
+func TestDebugger_Exec(t *testing.T) {
+	for _, tC := range []struct {
+		desc  string
+		lines []string
+		check func(*emu.Computer) bool
+		want  string
+	}{
+		{
+			"step",
+			[]string{"step"},
+			func(c *emu.Computer) bool { return c.PC == 0x03 && c.SP == 0x100 },
+			"=> 0003  CD 10 00  CALL $0010",
+		},
+		{
+			"empty line repeats the last command",
+			[]string{"step", "", ""},
+			func(c *emu.Computer) bool { return c.PC == 0x11 && c.C == 1 },
+			"=> 0011  32 80 00  STA $0080",
+		},
+		{
+			"next runs the subroutine",
+			[]string{"s", "next"},
+			func(c *emu.Computer) bool { return c.SP == 0x100 && c.C == 1 },
+			"",
+		},
+		{
+			"finish",
+			[]string{"s 2", "finish"},
+			func(c *emu.Computer) bool { return c.SP == 0x100 && c.C == 1 && c.Mem[0x80] == 0 },
+			"",
+		},
+		{
+			"break and continue",
+			[]string{"b 11", "c"},
+			func(c *emu.Computer) bool { return c.PC == 0x11 && c.C == 1 },
+			"breakpoint at 0011",
+		},
+		{
+			"watch and continue",
+			[]string{"watch 80", "c"},
+			func(c *emu.Computer) bool { return c.PC == 0x14 },
+			"watchpoint: 0080 written",
+		},
+		{
+			"set-reg",
+			[]string{"set hl 1234", "set a ff", "set pc 10"},
+			func(c *emu.Computer) bool { return c.HL() == 0x1234 && c.A == 0xFF && c.PC == 0x10 },
+			"A=FF  BC=0000  DE=0000  HL=1234  SP=0000  PC=0010",
+		},
+		{
+			"edit and dump memory",
+			[]string{"edit 80 48 49", "mem 80 4"},
+			func(c *emu.Computer) bool { return c.Mem[0x80] == 'H' && c.Mem[0x81] == 'I' },
+			"0080: 48 49 00 00                                      |HI..|",
+		},
+		{
+			"disassemble around the PC",
+			[]string{"s 2", "dis"},
+			func(c *emu.Computer) bool { return c.PC == 0x10 },
+			"   000D  00        NOP\n   000E  00        NOP\n   000F  00        NOP\n=> 0010  0C        INR C",
+		},
+		{
+			"backtrace",
+			[]string{"s 3", "bt"},
+			func(c *emu.Computer) bool { return c.PC == 0x11 },
+			"#0  0011  STA $0080\n#1  0003  CALL $0010  (SP+0)",
+		},
+		{
+			"history",
+			[]string{"regs", "s", "!1", "history"},
+			func(c *emu.Computer) bool { return c.PC == 0x03 },
+			"   1  regs\n   2  s\n   3  regs\n   4  history",
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			d, out := newTestDebugger(t)
+			for _, line := range tC.lines {
+				if d.exec(line) {
+					t.Fatalf("%q quit the debugger", line)
+				}
+			}
+			if !tC.check(d.c) {
+				t.Errorf("unexpected state after %q:\n%s\nA=%02X B=%02X C=%02X", tC.lines, out, d.c.A, d.c.B, d.c.C)
+			}
+			if !strings.Contains(out.String(), tC.want) {
+				t.Errorf("output of %q doesn't contain %q:\n%s", tC.lines, tC.want, out)
+			}
+		})
+	}
+}
+
+func TestDebugger_SaveLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state.json")
+
+	d, out := newTestDebugger(t)
+	d.exec("step 3")
+	d.exec("save " + file)
+	d.exec("set a 7")
+	d.exec("step")
+	d.exec("load " + file)
+
+	if d.c.PC != 0x11 || d.c.SP != 0xFE || d.c.A != 0 || d.c.Mem[0x80] != 0 {
+		t.Errorf("state not restored:\n%s", out)
+	}
+}
+
+func TestDebugger_Quit(t *testing.T) {
+	d, _ := newTestDebugger(t)
+	if !d.exec("quit") {
+		t.Error("quit didn't quit")
+	}
+}
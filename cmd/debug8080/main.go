@@ -0,0 +1,127 @@
+// debug8080 is an interactive debugger for 8080 programs running on the emulator.
+//
+// Usage:
+//
+//	debug8080 [-org addr] program
+//	debug8080 -dap stdio|addr
+//
+// ROM images are loaded at address 0 unless told otherwise, CP/M programs (.COM files) at 0x100, and Intel HEX files
+// (.HEX) at the addresses of their records. Type "help" at
+// the prompt to list the available commands. Pressing Enter on an empty line repeats the last command.
+//
+// With -dap, debug8080 serves the Debug Adapter Protocol instead, on its standard input and output or on the given
+// local TCP address, and the program to debug is given by the editor in its launch request.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/miguelff/8080/dap"
+	"github.com/miguelff/8080/emu"
+	"github.com/miguelff/8080/symbols"
+)
+
+func main() {
+	org := flag.String("org", "", "address the program is loaded at. Defaults to 100 for .COM files, and 0 otherwise. Ignored for .HEX files")
+	histFile := flag.String("history", defaultHistoryFile(), "file the command history is kept in")
+	dapAddr := flag.String("dap", "", "serve the Debug Adapter Protocol on stdio, or on the given local TCP address")
+	flag.Parse()
+
+	if *dapAddr != "" {
+		serveDAP(*dapAddr)
+		return
+	}
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: debug8080 [-org addr] program\n       debug8080 -dap stdio|addr")
+		os.Exit(2)
+	}
+
+	c, err := load(flag.Arg(0), *org)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	d := newDebugger(c, os.Stdout)
+	d.loadHistory(*histFile)
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		for range interrupts {
+			d.interrupt()
+		}
+	}()
+
+	in := bufio.NewScanner(os.Stdin)
+	d.where()
+	for {
+		fmt.Print("(8080) ")
+		if !in.Scan() {
+			break
+		}
+		if quit := d.exec(in.Text()); quit {
+			break
+		}
+	}
+
+	if err := d.saveHistory(*histFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// load loads the program at the given path, at the given origin if any
+func load(path, org string) (*emu.Computer, error) {
+	if strings.EqualFold(filepath.Ext(path), ".hex") {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return emu.LoadHex(f)
+	}
+
+	bin, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	addr := uint16(0)
+	if strings.EqualFold(filepath.Ext(path), ".com") {
+		addr = 0x100
+	}
+	if org != "" {
+		if addr, err = symbols.ParseAddress(org); err != nil {
+			return nil, err
+		}
+	}
+	return emu.LoadAt(bin, addr), nil
+}
+
+func serveDAP(addr string) {
+	var err error
+	if addr == "stdio" {
+		err = dap.Serve(os.Stdin, os.Stdout)
+	} else {
+		err = dap.ListenAndServe(addr)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".debug8080_history")
+}
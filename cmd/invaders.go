@@ -7,20 +7,43 @@ import (
 	"os"
 
 	"github.com/miguelff/8080/emu"
+	"github.com/miguelff/8080/gdbstub"
+	"github.com/miguelff/8080/symbols"
 )
 
+//go:embed "invaders.rom"
+var rom []byte
+
 func main() {
-	//go:embed "invaders.rom"
-	var rom []byte
 	var err error
 
 	debug := flag.String("d", "all", "debug opcode execution. Examples: '-d all' '-d \"C9 CD\"'")
+	gdb := flag.String("gdb", "", "wait for a GDB remote protocol client on the given address. Example: '-gdb localhost:1234'")
+	sym := flag.String("sym", "", "symbol file naming the addresses shown in the debug trace, or listing also showing their source code")
 	flag.Parse()
 
+	filter, err := emu.MakeDebugFilter(*debug)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 	c := emu.Load(rom)
+	if *sym != "" {
+		c.Symbols, err = symbols.Load(*sym)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if *gdb != "" {
+		fmt.Fprintf(os.Stderr, "waiting for a debugger on %s\n", *gdb)
+		err = gdbstub.New(c).ListenAndServe(*gdb)
+	}
 
 	for err == nil {
-		err = c.Step(emu.MakeDebugFilter(*debug))
+		err = c.Step(filter)
 		if err != nil {
 			fmt.Println(c)
 		}
@@ -0,0 +1,96 @@
+// link8080 links the relocatable objects written by asm8080 into a flat binary or an Intel HEX file.
+//
+// Usage:
+//
+//	link8080 [-o file] [-code addr] [-data addr] object.o80...
+//
+// The code segments of the objects are placed at -code, 0 by default, one after the other in the order given, and
+// their data segments at -data, or right after the code when left out. Addresses are hexadecimal. The external
+// symbols of each object are resolved to the public symbols of the others. See package obj for the format of
+// objects.
+//
+// The program is written next to the first object with a .bin extension unless told otherwise, or in Intel HEX
+// format when the file written has a .hex extension. Its map, telling where the segments of each object were placed
+// and the addresses of the public symbols, is written next to it with a .map extension. The map is understood by
+// the -sym flags of dasm and the emulator.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/miguelff/8080/encoding/ihex"
+	"github.com/miguelff/8080/link"
+	"github.com/miguelff/8080/obj"
+	"github.com/miguelff/8080/symbols"
+)
+
+func main() {
+	out := flag.String("o", "", "file written. Defaults to the first object with a .bin extension")
+	code := flag.String("code", "0", "address of the code segments")
+	data := flag.String("data", "", "address of the data segments. Defaults to right after the code")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: link8080 [-o file] [-code addr] [-data addr] object.o80...")
+		os.Exit(2)
+	}
+
+	var layout link.Layout
+	var err error
+	if layout.Code, err = symbols.ParseAddress(*code); err != nil {
+		fail(err)
+	}
+	if *data != "" {
+		if layout.Data, err = symbols.ParseAddress(*data); err != nil {
+			fail(err)
+		}
+	}
+
+	objects := make([]*obj.Object, flag.NArg())
+	for i, path := range flag.Args() {
+		if objects[i], err = obj.Load(path); err != nil {
+			fail(err)
+		}
+	}
+	p, err := link.Link(objects, layout)
+	if err != nil {
+		fail(err)
+	}
+
+	if *out == "" {
+		*out = strings.TrimSuffix(flag.Arg(0), filepath.Ext(flag.Arg(0))) + ".bin"
+	}
+	bin, origin := p.Binary()
+	if strings.EqualFold(filepath.Ext(*out), ".hex") {
+		write(*out, func(w io.Writer) error { return ihex.Write(w, p.Segments) })
+	} else if err := ioutil.WriteFile(*out, bin, 0644); err != nil {
+		fail(err)
+	}
+	write(strings.TrimSuffix(*out, filepath.Ext(*out))+".map", p.WriteMap)
+	fmt.Printf("%s: %d bytes at %04XH\n", *out, len(bin), origin)
+}
+
+// write creates the file at the given path with the contents written by the given function
+func write(path string, contents func(w io.Writer) error) {
+	f, err := os.Create(path)
+	if err != nil {
+		fail(err)
+	}
+	if err := contents(f); err != nil {
+		f.Close()
+		fail(err)
+	}
+	if err := f.Close(); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,245 @@
+// Package coverage compares the opcodes known to the disassembler, the emulator and the emulator tests, to find the
+// instructions not emulated or tested yet, and those the packages disagree on.
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"strconv"
+
+	"github.com/miguelff/8080/dasm"
+	"github.com/miguelff/8080/emu"
+	"github.com/miguelff/8080/encoding"
+	"github.com/miguelff/8080/isa"
+)
+
+// Report tells, for every opcode, what knows about it
+type Report struct {
+	// Defined are the opcodes package isa assigns an instruction to
+	Defined [256]bool
+	// Decoded are the opcodes decoded by package dasm
+	Decoded [256]bool
+	// Executed are the opcodes emulated by package emu
+	Executed [256]bool
+	// Tested are the opcodes executed by at least one case of the emulator tests
+	Tested [256]bool
+}
+
+// New creates the report of the opcodes, finding the tested ones in the given emulator tests, the source of
+// emu/computer_test.go
+func New(tests string) (*Report, error) {
+	var r Report
+	for op := 0; op < 256; op++ {
+		r.Defined[op] = isa.Opcodes[op].Defined()
+		_, err := dasm.Decode([]byte{byte(op), 0, 0}, 0)
+		r.Decoded[op] = err == nil
+		r.Executed[op] = emu.Implemented(byte(op))
+	}
+
+	tested, err := Tested(tests)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range tested {
+		r.Tested[op] = true
+	}
+	return &r, nil
+}
+
+// Pending are the opcodes defined and decoded, but still to be emulated. Disagreements reports the others not
+// executed, and those in the list executed already, so it must be updated as the emulator grows.
+var Pending = []byte{
+	0x07, 0x0B, 0x0F,
+	0x17, 0x1B, 0x1F,
+	0x22, 0x27, 0x2A, 0x2B, 0x2F,
+	0x34, 0x35, 0x37, 0x3A, 0x3B, 0x3F,
+	0x76,
+	0x96, 0x9E,
+	0xA6, 0xAE,
+	0xB6, 0xBE,
+	0xC0, 0xC1, 0xC4, 0xC5, 0xC6, 0xC8, 0xCA, 0xCC, 0xCE,
+	0xD0, 0xD1, 0xD2, 0xD4, 0xD6, 0xD8, 0xDA, 0xDC, 0xDE,
+	0xE0, 0xE1, 0xE2, 0xE3, 0xE4, 0xE5, 0xE8, 0xE9, 0xEA, 0xEB, 0xEC, 0xEE,
+	0xF0, 0xF1, 0xF2, 0xF3, 0xF4, 0xF5, 0xF6, 0xF8, 0xF9, 0xFA, 0xFB, 0xFC,
+}
+
+// Disagreements describes the opcodes the instruction set, the disassembler and the emulator disagree on: those
+// defined but not decoded or the other way around, those executed but undefined, and those decoded but not executed,
+// unless Pending, or Pending but executed
+func (r *Report) Disagreements() []string {
+	var pending [256]bool
+	for _, op := range Pending {
+		pending[op] = true
+	}
+
+	var found []string
+	for op := 0; op < 256; op++ {
+		switch {
+		case r.Defined[op] && !r.Decoded[op]:
+			found = append(found, fmt.Sprintf("%02X: %s isn't decoded", op, isa.Opcodes[op]))
+		case !r.Defined[op] && r.Decoded[op]:
+			found = append(found, fmt.Sprintf("%02X: undefined, but decoded", op))
+		case !r.Defined[op] && r.Executed[op]:
+			found = append(found, fmt.Sprintf("%02X: undefined, but executed", op))
+		case r.Decoded[op] && !r.Executed[op] && !pending[op]:
+			found = append(found, fmt.Sprintf("%02X: %s is decoded, but not executed", op, isa.Opcodes[op]))
+		case r.Executed[op] && pending[op]:
+			found = append(found, fmt.Sprintf("%02X: %s is executed, but still pending", op, isa.Opcodes[op]))
+		}
+	}
+	return found
+}
+
+// Write writes the report as a matrix of the opcodes, with their high nibble in the rows and their low nibble in the
+// columns. Each cell holds D when the opcode is decoded, E when it's executed and T when it's tested, or a dot in
+// their place. Undefined opcodes are left blank. A summary follows.
+func (r *Report) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, "  ")
+	for lo := 0; lo < 16; lo++ {
+		fmt.Fprintf(bw, "  x%X", lo)
+	}
+	fmt.Fprintln(bw)
+
+	var defined, decoded, executed, tested int
+	for hi := 0; hi < 16; hi++ {
+		fmt.Fprintf(bw, "%Xx", hi)
+		for lo := 0; lo < 16; lo++ {
+			op := hi<<4 | lo
+			cell := []byte("...")
+			for i, known := range []bool{r.Decoded[op], r.Executed[op], r.Tested[op]} {
+				if known {
+					cell[i] = "DET"[i]
+				}
+			}
+			if !r.Defined[op] && string(cell) == "..." {
+				cell = []byte("   ")
+			}
+			fmt.Fprintf(bw, " %s", cell)
+
+			defined += count(r.Defined[op])
+			decoded += count(r.Decoded[op])
+			executed += count(r.Executed[op])
+			tested += count(r.Tested[op])
+		}
+		fmt.Fprintln(bw)
+	}
+	fmt.Fprintf(bw, "\n%d opcodes defined: %d decoded, %d executed, %d tested\n", defined, decoded, executed, tested)
+	return bw.Flush()
+}
+
+// Missing describes the opcodes defined but not executed or not tested, like "DB: IN D8 isn't tested"
+func (r *Report) Missing() []string {
+	var found []string
+	for op := 0; op < 256; op++ {
+		switch {
+		case r.Defined[op] && !r.Executed[op]:
+			found = append(found, fmt.Sprintf("%02X: %s isn't executed", op, isa.Opcodes[op]))
+		case r.Defined[op] && !r.Tested[op]:
+			found = append(found, fmt.Sprintf("%02X: %s isn't tested", op, isa.Opcodes[op]))
+		}
+	}
+	return found
+}
+
+func count(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Tested returns the opcodes executed by the table-driven cases in the given source of the emulator tests, like those
+// of TestComputer_Step. A case is a {desc, init, want} literal, and its opcode is the byte its initial computer has
+// at PC, given as newComputer(CPU{..., PC: n}, ram("...")).
+func Tested(tests string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, tests, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []byte
+	ast.Inspect(f, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok || len(lit.Elts) < 2 {
+			return true
+		}
+		init, ok := call(lit.Elts[1], "newComputer")
+		if !ok || len(init.Args) != 2 {
+			return true
+		}
+
+		var op byte
+		if op, err = opcode(init); err != nil {
+			err = fmt.Errorf("%s: %v", fset.Position(init.Pos()), err)
+			return false
+		}
+		ops = append(ops, op)
+		return false
+	})
+	return ops, err
+}
+
+// opcode returns the opcode executed by the computer created by the given call to newComputer
+func opcode(init *ast.CallExpr) (byte, error) {
+	pc := 0
+	if cpu, ok := init.Args[0].(*ast.CompositeLit); ok {
+		for _, elt := range cpu.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			if key, ok := kv.Key.(*ast.Ident); !ok || key.Name != "PC" {
+				continue
+			}
+			v, ok := kv.Value.(*ast.BasicLit)
+			if !ok || v.Kind != token.INT {
+				return 0, fmt.Errorf("PC isn't a number")
+			}
+			n, err := strconv.ParseUint(v.Value, 0, 16)
+			if err != nil {
+				return 0, err
+			}
+			pc = int(n)
+		}
+	}
+
+	ram, ok := call(init.Args[1], "ram")
+	if !ok || len(ram.Args) != 1 {
+		return 0, fmt.Errorf("memory isn't given by ram")
+	}
+	v, ok := ram.Args[0].(*ast.BasicLit)
+	if !ok || v.Kind != token.STRING {
+		return 0, fmt.Errorf("memory isn't a string")
+	}
+	s, err := strconv.Unquote(v.Value)
+	if err != nil {
+		return 0, err
+	}
+	mem, err := encoding.ParseHex(s)
+	if err != nil {
+		return 0, err
+	}
+	if pc >= len(mem) {
+		return 0, fmt.Errorf("PC %04X out of the memory", pc)
+	}
+	return mem[pc], nil
+}
+
+// call returns the expression as a call to the function with the given name, if it's one
+func call(expr ast.Expr, name string) (*ast.CallExpr, bool) {
+	c, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return nil, false
+	}
+	fun, ok := c.Fun.(*ast.Ident)
+	return c, ok && fun.Name == name
+}
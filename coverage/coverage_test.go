@@ -0,0 +1,139 @@
+package coverage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miguelff/8080/isa"
+)
+
+// TestReport prints the coverage of the opcodes, failing when the instruction set, the disassembler and the
+// emulator disagree on which opcodes exist
+func TestReport(t *testing.T) {
+	r, err := New("../emu/computer_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("\n%s", buf.String())
+
+	for _, d := range r.Disagreements() {
+		t.Error(d)
+	}
+	for op := range r.Tested {
+		if r.Tested[op] && !r.Executed[op] {
+			t.Errorf("%02X: tested, but not executed", op)
+		}
+	}
+}
+
+func TestReport_Disagreements(t *testing.T) {
+	for _, tC := range []struct {
+		desc string
+		op   byte
+		init func(r *Report, op byte)
+		want string
+	}{
+		{"agreed", 0x00, func(r *Report, op byte) {}, ""},
+		{"not decoded", 0x00, func(r *Report, op byte) { r.Decoded[op] = false }, "00: NOP isn't decoded"},
+		{"undefined, but decoded", 0xCB, func(r *Report, op byte) { r.Decoded[op] = true }, "CB: undefined, but decoded"},
+		{"undefined, but executed", 0xCB, func(r *Report, op byte) { r.Executed[op] = true }, "CB: undefined, but executed"},
+		{"not executed", 0x00, func(r *Report, op byte) { r.Executed[op] = false }, "00: NOP is decoded, but not executed"},
+		{"pending", Pending[0], func(r *Report, op byte) { r.Executed[op] = false }, ""},
+		{"pending, but executed", Pending[0], func(r *Report, op byte) {}, "07: RLC is executed, but still pending"},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			var r Report
+			for op := range r.Defined {
+				r.Defined[op] = isa.Opcodes[op].Defined()
+				r.Decoded[op], r.Executed[op] = r.Defined[op], r.Defined[op]
+			}
+			for _, op := range Pending {
+				r.Executed[op] = false
+			}
+			r.Executed[tC.op] = r.Defined[tC.op]
+			tC.init(&r, tC.op)
+
+			if got := strings.Join(r.Disagreements(), "\n"); got != tC.want {
+				t.Errorf("got %q, want %q", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestTested(t *testing.T) {
+	for _, tC := range []struct {
+		desc   string
+		source string
+		want   []byte
+		err    string
+	}{
+		{
+			desc: "cases",
+			source: `{
+				{"NOP", newComputer(CPU{}, ram("00")), newComputer(CPU{PC: 1}, ram("00"))},
+				{"ADD B", newComputer(CPU{B: 1}, ram("80")), nil},
+			}`,
+			want: []byte{0x00, 0x80},
+		},
+		{
+			desc:   "opcode at PC",
+			source: `{{"RST 0", newComputer(CPU{A: 1, PC: 0x02}, ram("00 00 C7")), nil}}`,
+			want:   []byte{0xC7},
+		},
+		{
+			desc:   "other computers",
+			source: `{newComputer(CPU{}, ram("76")), {"desc", Load(nil)}}`,
+		},
+		{
+			desc:   "PC out of the memory",
+			source: `{{"NOP", newComputer(CPU{PC: 4}, ram("00")), nil}}`,
+			err:    "PC 0004 out of the memory",
+		},
+		{
+			desc:   "memory not given by ram",
+			source: `{{"NOP", newComputer(CPU{}, make([]byte, 1)), nil}}`,
+			err:    "memory isn't given by ram",
+		},
+		{
+			desc:   "invalid memory",
+			source: `{{"NOP", newComputer(CPU{}, ram("0G")), nil}}`,
+			err:    "line 1, column 2: invalid hexadecimal digit 'G'",
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "computer_test.go")
+			source := "package emu\n\nvar cases = []struct{}" + tC.source + "\n"
+			if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Tested(path)
+			if tC.err != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), tC.err) {
+					t.Fatalf("got error %v, want %q", err, tC.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tC.want) {
+				t.Errorf("got % X, want % X", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestTested_NoFile(t *testing.T) {
+	if _, err := Tested(filepath.Join(t.TempDir(), "missing.go")); !os.IsNotExist(err) {
+		t.Errorf("got error %v, want a missing file", err)
+	}
+}
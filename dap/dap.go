@@ -0,0 +1,834 @@
+// dap serves the Debug Adapter Protocol (DAP) for the emulator, so editors speaking it can debug 8080 programs.
+//
+// The launch request accepts these arguments:
+//
+//	program      path of the ROM image, CP/M program (.COM) or Intel HEX file (.HEX) to debug. Required.
+//	org          address the program is loaded at. Defaults to 0x100 for .COM files, and 0 otherwise. HEX files are
+//	             loaded at the addresses of their records.
+//	lineMap      path of the line map of the program, written by asm8080 -lines, to set breakpoints on source lines.
+//	             See LineMap.
+//	stopOnEntry  stop before executing the first instruction.
+package dap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/miguelff/8080/dasm"
+	"github.com/miguelff/8080/emu"
+	"github.com/miguelff/8080/symbols"
+)
+
+const (
+	threadID = 1
+	// registersRef is the variables reference of the registers scope
+	registersRef = 1
+)
+
+// message is the part common to requests, responses and events
+type message struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command,omitempty"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type response struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type event struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+// ListenAndServe listens on the given local TCP address, for instance "localhost:4711", and serves the debugging
+// sessions of the clients connecting to it, one after the other. It only returns when the listener fails.
+func ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		_ = Serve(conn, conn)
+		_ = conn.Close()
+	}
+}
+
+// runFunc is one of the emu.Computer methods running the program: Run, Next or Finish
+type runFunc func(*emu.Computer, *emu.Breakpoints, func() bool) emu.Stop
+
+// step executes a single instruction
+func step(c *emu.Computer, bp *emu.Breakpoints, _ func() bool) emu.Stop {
+	return c.Run(bp, func() bool { return true })
+}
+
+// session holds the state of the connection with an editor
+type session struct {
+	wmu sync.Mutex
+	w   *bufio.Writer
+	seq int
+
+	c     *emu.Computer
+	bp    *emu.Breakpoints
+	lines *LineMap
+	// srcBreaks are the breakpoints set on the lines of each source file
+	srcBreaks map[string][]uint16
+	// instBreaks are the breakpoints set on instructions
+	instBreaks  []uint16
+	stopOnEntry bool
+	// resume is run after responding to the current request, so events it reports follow the response
+	resume func()
+
+	// mu guards running and handover
+	mu      sync.Mutex
+	running bool
+	// handover asks the running program to hand its stop over through runDone, instead of reporting it
+	handover bool
+	runDone  chan emu.Stop
+	// interrupted is set to 1 to stop the running program
+	interrupted int32
+}
+
+// Serve runs a debugging session, reading requests from r and writing responses and events to w. It returns when the
+// editor disconnects, or r is exhausted.
+func Serve(r io.Reader, w io.Writer) error {
+	s := &session{
+		w:         bufio.NewWriter(w),
+		srcBreaks: make(map[string][]uint16),
+		runDone:   make(chan emu.Stop),
+	}
+	defer s.pause()
+
+	tp := textproto.NewReader(bufio.NewReader(r))
+	for {
+		msg, err := readMessage(tp)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Type != "request" {
+			continue
+		}
+
+		s.resume = nil
+		body, err := s.handle(msg)
+		if err := s.respond(msg, body, err); err != nil {
+			return err
+		}
+		if s.resume != nil {
+			s.resume()
+		}
+		switch msg.Command {
+		case "launch":
+			if err == nil {
+				s.emit("initialized", nil)
+			}
+		case "disconnect", "terminate":
+			s.emit("terminated", nil)
+			return nil
+		}
+	}
+}
+
+func readMessage(tp *textproto.Reader) (*message, error) {
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || (err == io.ErrUnexpectedEOF && len(header) == 0) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(tp.R, content); err != nil {
+		return nil, err
+	}
+	msg := new(message)
+	if err := json.Unmarshal(content, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// send writes a response or event, filling its sequence number
+func (s *session) send(seq func(int), msg interface{}) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	s.seq++
+	seq(s.seq)
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(content), content); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+func (s *session) respond(req *message, body interface{}, err error) error {
+	resp := &response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+	}
+	return s.send(func(seq int) { resp.Seq = seq }, resp)
+}
+
+func (s *session) emit(name string, body interface{}) {
+	ev := &event{Type: "event", Event: name, Body: body}
+	_ = s.send(func(seq int) { ev.Seq = seq }, ev)
+}
+
+// errRunning is returned by the requests that need the program to be stopped
+var errRunning = fmt.Errorf("the program is running")
+
+func (s *session) isRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// handle executes a request, and returns the body of the response
+func (s *session) handle(req *message) (interface{}, error) {
+	switch req.Command {
+	case "initialize":
+		return capabilities, nil
+	case "launch":
+		return nil, s.launch(req.Arguments)
+	case "disconnect", "terminate":
+		s.pause()
+		return nil, nil
+	case "setExceptionBreakpoints":
+		return nil, nil
+	case "threads":
+		return map[string]interface{}{"threads": []interface{}{map[string]interface{}{"id": threadID, "name": "8080"}}}, nil
+	case "pause":
+		atomic.StoreInt32(&s.interrupted, 1)
+		return nil, nil
+	}
+
+	if s.c == nil {
+		return nil, fmt.Errorf("no program launched")
+	}
+
+	switch req.Command {
+	case "setBreakpoints":
+		return s.setBreakpoints(req.Arguments)
+	case "setInstructionBreakpoints":
+		return s.setInstructionBreakpoints(req.Arguments)
+	case "configurationDone":
+		s.resume = func() {
+			if s.stopOnEntry {
+				s.emit("stopped", stoppedBody("entry", ""))
+			} else {
+				s.start((*emu.Computer).Run, false)
+			}
+		}
+		return nil, nil
+	}
+
+	if s.isRunning() {
+		return nil, errRunning
+	}
+
+	switch req.Command {
+	case "continue":
+		s.resume = func() { s.start((*emu.Computer).Run, false) }
+		return map[string]interface{}{"allThreadsContinued": true}, nil
+	case "next":
+		s.resume = func() { s.start((*emu.Computer).Next, true) }
+		return nil, nil
+	case "stepIn":
+		s.resume = func() { s.start(step, true) }
+		return nil, nil
+	case "stepOut":
+		s.resume = func() { s.start((*emu.Computer).Finish, true) }
+		return nil, nil
+	case "stackTrace":
+		return s.stackTrace(), nil
+	case "scopes":
+		scope := map[string]interface{}{
+			"name":               "Registers",
+			"presentationHint":   "registers",
+			"variablesReference": registersRef,
+			"expensive":          false,
+		}
+		return map[string]interface{}{"scopes": []interface{}{scope}}, nil
+	case "variables":
+		return s.variables(req.Arguments)
+	case "setVariable":
+		return s.setVariable(req.Arguments)
+	case "readMemory":
+		return s.readMemory(req.Arguments)
+	case "writeMemory":
+		return s.writeMemory(req.Arguments)
+	case "disassemble":
+		return s.disassemble(req.Arguments)
+	}
+	return nil, fmt.Errorf("unsupported request %q", req.Command)
+}
+
+var capabilities = map[string]interface{}{
+	"supportsConfigurationDoneRequest": true,
+	"supportsSetVariable":              true,
+	"supportsReadMemoryRequest":        true,
+	"supportsWriteMemoryRequest":       true,
+	"supportsDisassembleRequest":       true,
+	"supportsInstructionBreakpoints":   true,
+	"supportsSteppingGranularity":      false,
+	"supportsTerminateRequest":         true,
+}
+
+func (s *session) launch(raw json.RawMessage) error {
+	var args struct {
+		Program     string `json:"program"`
+		Org         *int   `json:"org"`
+		LineMap     string `json:"lineMap"`
+		StopOnEntry bool   `json:"stopOnEntry"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return err
+	}
+	if args.Program == "" {
+		return fmt.Errorf("the program to debug is required")
+	}
+
+	c, err := load(args.Program, args.Org)
+	if err != nil {
+		return err
+	}
+
+	s.lines = NewLineMap()
+	if args.LineMap != "" {
+		if s.lines, err = LoadLineMap(args.LineMap); err != nil {
+			return err
+		}
+	}
+	s.c = c
+	s.bp = emu.NewBreakpoints()
+	s.stopOnEntry = args.StopOnEntry
+	return nil
+}
+
+// load loads the program at the given path, at the given origin if any
+func load(program string, org *int) (*emu.Computer, error) {
+	if strings.EqualFold(filepath.Ext(program), ".hex") {
+		f, err := os.Open(program)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return emu.LoadHex(f)
+	}
+
+	bin, err := ioutil.ReadFile(program)
+	if err != nil {
+		return nil, err
+	}
+	addr := 0
+	if strings.EqualFold(filepath.Ext(program), ".com") {
+		addr = 0x100
+	}
+	if org != nil {
+		addr = *org
+	}
+	if addr < 0 || addr > 0xFFFF {
+		return nil, fmt.Errorf("invalid origin %d", addr)
+	}
+	return emu.LoadAt(bin, uint16(addr)), nil
+}
+
+// start runs the program in the background, and reports a stopped event when it stops. step tells whether the
+// program was started by a stepping request, so stops requested by the run function are reported as steps.
+func (s *session) start(run runFunc, step bool) {
+	s.mu.Lock()
+	s.running = true
+	s.mu.Unlock()
+	atomic.StoreInt32(&s.interrupted, 0)
+
+	go func() {
+		st := run(s.c, s.bp, func() bool { return atomic.LoadInt32(&s.interrupted) != 0 })
+
+		s.mu.Lock()
+		s.running = false
+		handover := s.handover
+		s.handover = false
+		s.mu.Unlock()
+
+		if handover {
+			s.runDone <- st
+			return
+		}
+		s.stopped(st, step && atomic.LoadInt32(&s.interrupted) == 0)
+	}()
+}
+
+// stopped reports why the program stopped
+func (s *session) stopped(st emu.Stop, step bool) {
+	switch st.Reason {
+	case emu.StopBreakpoint:
+		s.emit("stopped", stoppedBody("breakpoint", ""))
+	case emu.StopWatchpoint:
+		s.emit("stopped", stoppedBody("data breakpoint", fmt.Sprintf("%04X written", st.Addr)))
+	case emu.StopError:
+		s.emit("stopped", stoppedBody("exception", st.Err.Error()))
+	case emu.StopRequested:
+		if step {
+			s.emit("stopped", stoppedBody("step", ""))
+		} else {
+			s.emit("stopped", stoppedBody("pause", ""))
+		}
+	}
+}
+
+func stoppedBody(reason, text string) map[string]interface{} {
+	body := map[string]interface{}{"reason": reason, "threadId": threadID, "allThreadsStopped": true}
+	if text != "" {
+		body["text"] = text
+	}
+	return body
+}
+
+// pause stops the running program without reporting it, and returns how it stopped and whether it was running
+func (s *session) pause() (emu.Stop, bool) {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return emu.Stop{}, false
+	}
+	s.handover = true
+	s.mu.Unlock()
+
+	atomic.StoreInt32(&s.interrupted, 1)
+	return <-s.runDone, true
+}
+
+// whileStopped applies the given change to the state of the program. If the program is running, it's paused while
+// the change is applied, and resumed afterwards.
+func (s *session) whileStopped(change func()) {
+	st, running := s.pause()
+	change()
+	if !running {
+		return
+	}
+	if st.Reason == emu.StopRequested {
+		s.start((*emu.Computer).Run, false)
+	} else {
+		s.stopped(st, false)
+	}
+}
+
+// breakpoint is the description of a breakpoint sent to the editor
+type breakpoint struct {
+	Verified             bool   `json:"verified"`
+	Line                 int    `json:"line,omitempty"`
+	Message              string `json:"message,omitempty"`
+	InstructionReference string `json:"instructionReference,omitempty"`
+}
+
+func (s *session) setBreakpoints(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Source struct {
+			Path string `json:"path"`
+		} `json:"source"`
+		Breakpoints []struct {
+			Line int `json:"line"`
+		} `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+
+	var addrs []uint16
+	bps := make([]breakpoint, len(args.Breakpoints))
+	for i, req := range args.Breakpoints {
+		bps[i].Line = req.Line
+		found := s.lines.Addrs(args.Source.Path, req.Line)
+		if len(found) == 0 {
+			bps[i].Message = "no code at this line"
+			continue
+		}
+		bps[i].Verified = true
+		bps[i].InstructionReference = memoryReference(found[0])
+		addrs = append(addrs, found[0])
+	}
+
+	s.whileStopped(func() {
+		s.srcBreaks[filepath.Clean(args.Source.Path)] = addrs
+		s.rebuildBreakpoints()
+	})
+	return map[string]interface{}{"breakpoints": bps}, nil
+}
+
+func (s *session) setInstructionBreakpoints(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Breakpoints []struct {
+			InstructionReference string `json:"instructionReference"`
+			Offset               int    `json:"offset"`
+		} `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+
+	var addrs []uint16
+	bps := make([]breakpoint, len(args.Breakpoints))
+	for i, req := range args.Breakpoints {
+		addr, err := parseReference(req.InstructionReference, req.Offset)
+		if err != nil {
+			bps[i].Message = err.Error()
+			continue
+		}
+		bps[i].Verified = true
+		bps[i].InstructionReference = memoryReference(addr)
+		if loc, ok := s.lines.Location(addr); ok {
+			bps[i].Line = loc.Line
+		}
+		addrs = append(addrs, addr)
+	}
+
+	s.whileStopped(func() {
+		s.instBreaks = addrs
+		s.rebuildBreakpoints()
+	})
+	return map[string]interface{}{"breakpoints": bps}, nil
+}
+
+// rebuildBreakpoints sets the breakpoints of the computer to the union of those set on source lines and instructions
+func (s *session) rebuildBreakpoints() {
+	s.bp = emu.NewBreakpoints()
+	for _, addrs := range s.srcBreaks {
+		for _, addr := range addrs {
+			s.bp.Break(addr)
+		}
+	}
+	for _, addr := range s.instBreaks {
+		s.bp.Break(addr)
+	}
+}
+
+func (s *session) stackTrace() interface{} {
+	pc := s.c.PC
+	asm, _ := s.decode(pc)
+	frame := map[string]interface{}{
+		"id":                          1,
+		"name":                        fmt.Sprintf("%04X  %s", pc, asm),
+		"line":                        0,
+		"column":                      0,
+		"instructionPointerReference": memoryReference(pc),
+	}
+	if loc, ok := s.lines.Location(pc); ok {
+		frame["source"] = map[string]interface{}{"name": filepath.Base(loc.File), "path": loc.File}
+		frame["line"] = loc.Line
+		frame["column"] = 1
+	}
+	return map[string]interface{}{"stackFrames": []interface{}{frame}, "totalFrames": 1}
+}
+
+// register is a register of the CPU, as shown in the registers scope
+type register struct {
+	name string
+	get  func(c *emu.Computer) uint16
+	set  func(c *emu.Computer, v uint16)
+	bits int
+}
+
+var registers = []register{
+	{"A", func(c *emu.Computer) uint16 { return uint16(c.A) }, func(c *emu.Computer, v uint16) { c.A = byte(v) }, 8},
+	{"B", func(c *emu.Computer) uint16 { return uint16(c.B) }, func(c *emu.Computer, v uint16) { c.B = byte(v) }, 8},
+	{"C", func(c *emu.Computer) uint16 { return uint16(c.C) }, func(c *emu.Computer, v uint16) { c.C = byte(v) }, 8},
+	{"D", func(c *emu.Computer) uint16 { return uint16(c.D) }, func(c *emu.Computer, v uint16) { c.D = byte(v) }, 8},
+	{"E", func(c *emu.Computer) uint16 { return uint16(c.E) }, func(c *emu.Computer, v uint16) { c.E = byte(v) }, 8},
+	{"H", func(c *emu.Computer) uint16 { return uint16(c.H) }, func(c *emu.Computer, v uint16) { c.H = byte(v) }, 8},
+	{"L", func(c *emu.Computer) uint16 { return uint16(c.L) }, func(c *emu.Computer, v uint16) { c.L = byte(v) }, 8},
+	{"BC", (*emu.Computer).BC, func(c *emu.Computer, v uint16) { c.B, c.C = byte(v>>8), byte(v) }, 16},
+	{"DE", (*emu.Computer).DE, func(c *emu.Computer, v uint16) { c.D, c.E = byte(v>>8), byte(v) }, 16},
+	{"HL", (*emu.Computer).HL, func(c *emu.Computer, v uint16) { c.H, c.L = byte(v>>8), byte(v) }, 16},
+	{"SP", func(c *emu.Computer) uint16 { return c.SP }, func(c *emu.Computer, v uint16) { c.SP = v }, 16},
+	{"PC", func(c *emu.Computer) uint16 { return c.PC }, func(c *emu.Computer, v uint16) { c.PC = v }, 16},
+	{"F", func(c *emu.Computer) uint16 { return uint16(c.Flags) }, func(c *emu.Computer, v uint16) { c.Flags = emu.Flags(v) }, 8},
+}
+
+func (r register) format(c *emu.Computer) string {
+	if r.bits == 8 {
+		v := fmt.Sprintf("0x%02X", r.get(c))
+		if r.name == "F" {
+			v += fmt.Sprintf(" (%s)", c.Flags)
+		}
+		return v
+	}
+	return fmt.Sprintf("0x%04X", r.get(c))
+}
+
+func (s *session) variables(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+
+	vars := []interface{}{}
+	if args.VariablesReference == registersRef {
+		for _, r := range registers {
+			v := map[string]interface{}{"name": r.name, "value": r.format(s.c), "variablesReference": 0}
+			if r.bits == 16 {
+				v["memoryReference"] = memoryReference(r.get(s.c))
+			}
+			vars = append(vars, v)
+		}
+	}
+	return map[string]interface{}{"variables": vars}, nil
+}
+
+func (s *session) setVariable(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		VariablesReference int    `json:"variablesReference"`
+		Name               string `json:"name"`
+		Value              string `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+
+	for _, r := range registers {
+		if args.VariablesReference != registersRef || !strings.EqualFold(r.name, args.Name) {
+			continue
+		}
+		v, err := symbols.ParseAddress(args.Value)
+		if err != nil {
+			return nil, err
+		}
+		if int(v) >= 1<<uint(r.bits) {
+			return nil, fmt.Errorf("%s is a %d-bit register", r.name, r.bits)
+		}
+		r.set(s.c, v)
+		return map[string]interface{}{"value": r.format(s.c)}, nil
+	}
+	return nil, fmt.Errorf("unknown variable %q", args.Name)
+}
+
+func (s *session) readMemory(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		MemoryReference string `json:"memoryReference"`
+		Offset          int    `json:"offset"`
+		Count           int    `json:"count"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	addr, err := parseReference(args.MemoryReference, args.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	end := int(addr) + args.Count
+	if end > len(s.c.Mem) {
+		end = len(s.c.Mem)
+	}
+	var data []byte
+	if int(addr) < end {
+		data = s.c.Mem[addr:end]
+	}
+	return map[string]interface{}{
+		"address":         memoryReference(addr),
+		"data":            base64.StdEncoding.EncodeToString(data),
+		"unreadableBytes": args.Count - len(data),
+	}, nil
+}
+
+func (s *session) writeMemory(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		MemoryReference string `json:"memoryReference"`
+		Offset          int    `json:"offset"`
+		Data            string `json:"data"`
+		AllowPartial    bool   `json:"allowPartial"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	addr, err := parseReference(args.MemoryReference, args.Offset)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(args.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	if int(addr)+len(data) > len(s.c.Mem) {
+		if !args.AllowPartial {
+			return nil, fmt.Errorf("%04X is out of memory", int(addr)+len(data)-1)
+		}
+		if int(addr) >= len(s.c.Mem) {
+			data = nil
+		} else {
+			data = data[:len(s.c.Mem)-int(addr)]
+		}
+	}
+	copy(s.c.Mem[addr:], data)
+	return map[string]interface{}{"bytesWritten": len(data)}, nil
+}
+
+func (s *session) disassemble(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		MemoryReference   string `json:"memoryReference"`
+		Offset            int    `json:"offset"`
+		InstructionOffset int    `json:"instructionOffset"`
+		InstructionCount  int    `json:"instructionCount"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	addr, err := parseReference(args.MemoryReference, args.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	// find the addresses of the instructions preceding the one at addr, when asked for them
+	var addrs []int
+	if args.InstructionOffset < 0 {
+		addrs = s.before(addr, -args.InstructionOffset)
+	}
+	for pos, i := int(addr), 0; len(addrs) < args.InstructionCount+(-args.InstructionOffset) || i < args.InstructionOffset; i++ {
+		if i >= args.InstructionOffset {
+			addrs = append(addrs, pos)
+		}
+		if pos < len(s.c.Mem) {
+			_, size := s.decode(uint16(pos))
+			pos += size
+		} else {
+			pos++
+		}
+	}
+	if args.InstructionOffset < 0 {
+		addrs = addrs[:args.InstructionCount]
+	}
+
+	instructions := make([]interface{}, 0, len(addrs))
+	for _, pos := range addrs {
+		if pos < 0 || pos >= len(s.c.Mem) {
+			instructions = append(instructions, map[string]interface{}{
+				"address":          memoryReference(uint16(pos)),
+				"instruction":      "??",
+				"presentationHint": "invalid",
+			})
+			continue
+		}
+		asm, size := s.decode(uint16(pos))
+		var raw []string
+		for i := 0; i < size; i++ {
+			raw = append(raw, fmt.Sprintf("%02X", s.c.Mem[pos+i]))
+		}
+		inst := map[string]interface{}{
+			"address":          memoryReference(uint16(pos)),
+			"instructionBytes": strings.Join(raw, " "),
+			"instruction":      asm,
+		}
+		if loc, ok := s.lines.Location(uint16(pos)); ok {
+			inst["location"] = map[string]interface{}{"name": filepath.Base(loc.File), "path": loc.File}
+			inst["line"] = loc.Line
+		}
+		instructions = append(instructions, inst)
+	}
+	return map[string]interface{}{"instructions": instructions}, nil
+}
+
+// before returns the addresses of the n instructions preceding addr. Code can't be reliably disassembled backwards,
+// so the disassembly starts far enough before addr, and the starting point is moved until it lands exactly on addr.
+// When there aren't enough instructions, the missing ones are filled with negative (invalid) addresses.
+func (s *session) before(addr uint16, n int) []int {
+	for skew := 0; skew < 3; skew++ {
+		start := int(addr) - 3*n - skew
+		if start < 0 {
+			start = 0
+		}
+		var addrs []int
+		pos := start
+		for pos < int(addr) {
+			addrs = append(addrs, pos)
+			_, size := s.decode(uint16(pos))
+			pos += size
+		}
+		if pos != int(addr) {
+			continue
+		}
+		if len(addrs) > n {
+			addrs = addrs[len(addrs)-n:]
+		}
+		for len(addrs) < n {
+			addrs = append([]int{-1}, addrs...)
+		}
+		return addrs
+	}
+
+	addrs := make([]int, n)
+	for i := range addrs {
+		addrs[i] = int(addr) - n + i
+	}
+	return addrs
+}
+
+// decode disassembles the instruction at the given address, and returns it along with its size. Undefined opcodes
+// are shown as data.
+func (s *session) decode(addr uint16) (string, int) {
+	mem := s.c.Mem
+	if int(addr) >= len(mem) {
+		return "??", 1
+	}
+	if inst, err := dasm.Decode(mem[addr:], addr); err == nil {
+		return inst.String(), inst.Size
+	}
+	return fmt.Sprintf("DB $%02X", mem[addr]), 1
+}
+
+func memoryReference(addr uint16) string {
+	return fmt.Sprintf("0x%04X", addr)
+}
+
+// parseReference parses a memory or instruction reference, and adds the given offset to it
+func parseReference(ref string, offset int) (uint16, error) {
+	v, err := symbols.ParseAddress(ref)
+	if err != nil {
+		return 0, err
+	}
+	addr := int(v) + offset
+	if addr < 0 || addr > 0xFFFF {
+		return 0, fmt.Errorf("address %X is out of range", addr)
+	}
+	return uint16(addr), nil
+}
@@ -0,0 +1,302 @@
+package dap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguelff/8080/asm"
+)
+
+// program is the source of the test program, also debugged by the tests of cmd/debug8080
+const program = "testdata/prog.asm"
+
+// client talks to a session served through pipes
+type client struct {
+	t   *testing.T
+	w   io.Writer
+	seq int
+	in  chan map[string]interface{}
+}
+
+func startClient(t *testing.T) *client {
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+	go func() {
+		_ = Serve(reqR, respW)
+		respW.Close()
+	}()
+
+	c := &client{t: t, w: reqW, in: make(chan map[string]interface{}, 100)}
+	go func() {
+		defer close(c.in)
+		tp := textproto.NewReader(bufio.NewReader(respR))
+		for {
+			header, err := tp.ReadMIMEHeader()
+			if err != nil {
+				return
+			}
+			var n int
+			fmt.Sscan(header.Get("Content-Length"), &n)
+			content := make([]byte, n)
+			if _, err := io.ReadFull(tp.R, content); err != nil {
+				return
+			}
+			msg := make(map[string]interface{})
+			if err := json.Unmarshal(content, &msg); err != nil {
+				t.Errorf("invalid message %s", content)
+				return
+			}
+			c.in <- msg
+		}
+	}()
+	t.Cleanup(func() { reqW.Close() })
+	return c
+}
+
+// request sends a request and returns its response. Events received meanwhile are dropped.
+func (c *client) request(command string, args interface{}) map[string]interface{} {
+	c.t.Helper()
+	c.seq++
+	content, _ := json.Marshal(map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+	fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(content), content)
+
+	for {
+		msg := c.next()
+		if msg["type"] == "response" && msg["request_seq"] == float64(c.seq) {
+			if msg["success"] != true {
+				c.t.Fatalf("%s failed: %v", command, msg["message"])
+			}
+			body, _ := msg["body"].(map[string]interface{})
+			return body
+		}
+	}
+}
+
+// event waits for the given event, and returns its body
+func (c *client) event(name string) map[string]interface{} {
+	c.t.Helper()
+	for {
+		msg := c.next()
+		if msg["type"] == "event" && msg["event"] == name {
+			body, _ := msg["body"].(map[string]interface{})
+			return body
+		}
+	}
+}
+
+func (c *client) next() map[string]interface{} {
+	c.t.Helper()
+	select {
+	case msg, ok := <-c.in:
+		if !ok {
+			c.t.Fatal("the session ended")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for a message")
+	}
+	return nil
+}
+
+// launch starts debugging the test program, stopped on entry
+func (c *client) launch() {
+	c.t.Helper()
+	dir := c.t.TempDir()
+	prog := filepath.Join(dir, "prog.bin")
+	lines := filepath.Join(dir, "prog.lines")
+	p, err := asm.AssembleFile(program)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	bin, _ := p.Binary()
+	if err := ioutil.WriteFile(prog, bin, 0644); err != nil {
+		c.t.Fatal(err)
+	}
+	var lineMap bytes.Buffer
+	if err := p.WriteLineMap(&lineMap, dir); err != nil {
+		c.t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(lines, lineMap.Bytes(), 0644); err != nil {
+		c.t.Fatal(err)
+	}
+
+	c.request("initialize", map[string]interface{}{"adapterID": "8080"})
+	c.request("launch", map[string]interface{}{"program": prog, "lineMap": lines, "stopOnEntry": true})
+	c.event("initialized")
+	c.request("configurationDone", nil)
+	if reason := c.event("stopped")["reason"]; reason != "entry" {
+		c.t.Fatalf("stopped because of %v, want entry", reason)
+	}
+}
+
+func (c *client) registers() map[string]string {
+	c.t.Helper()
+	regs := make(map[string]string)
+	for _, v := range c.request("variables", map[string]interface{}{"variablesReference": registersRef})["variables"].([]interface{}) {
+		v := v.(map[string]interface{})
+		regs[v["name"].(string)] = v["value"].(string)
+	}
+	return regs
+}
+
+func TestServe(t *testing.T) {
+	for _, tC := range []struct {
+		desc string
+		run  func(c *client) string
+		want string
+	}{
+		{
+			"breakpoint on a source line",
+			func(c *client) string {
+				bps := c.request("setBreakpoints", map[string]interface{}{
+					"source":      map[string]interface{}{"path": "/elsewhere/prog.asm"},
+					"breakpoints": []interface{}{map[string]interface{}{"line": 7}, map[string]interface{}{"line": 5}},
+				})["breakpoints"].([]interface{})
+				c.request("continue", nil)
+				reason := c.event("stopped")["reason"]
+				return fmt.Sprintf("%v %v %v %v", reason, bps[0].(map[string]interface{})["verified"], bps[1].(map[string]interface{})["verified"], c.registers()["PC"])
+			},
+			"breakpoint true false 0x0011",
+		},
+		{
+			"instruction breakpoint",
+			func(c *client) string {
+				c.request("setInstructionBreakpoints", map[string]interface{}{
+					"breakpoints": []interface{}{map[string]interface{}{"instructionReference": "0x0010", "offset": 1}},
+				})
+				c.request("continue", nil)
+				c.event("stopped")
+				return c.registers()["PC"]
+			},
+			"0x0011",
+		},
+		{
+			"next over a call",
+			func(c *client) string {
+				c.request("next", nil)
+				c.event("stopped")
+				c.request("next", nil)
+				reason := c.event("stopped")["reason"]
+				regs := c.registers()
+				return fmt.Sprint(reason, " ", regs["SP"], " ", regs["C"])
+			},
+			"step 0x0100 0x01",
+		},
+		{
+			"step in and out",
+			func(c *client) string {
+				for i := 0; i < 3; i++ {
+					c.request("stepIn", nil)
+					c.event("stopped")
+				}
+				frame := c.request("stackTrace", map[string]interface{}{"threadId": threadID})["stackFrames"].([]interface{})[0].(map[string]interface{})
+				line := frame["line"]
+				c.request("stepOut", nil)
+				c.event("stopped")
+				return fmt.Sprint(line, " ", c.registers()["SP"])
+			},
+			"7 0x0100",
+		},
+		{
+			"set a register",
+			func(c *client) string {
+				c.request("setVariable", map[string]interface{}{"variablesReference": registersRef, "name": "hl", "value": "0x1234"})
+				return c.registers()["HL"]
+			},
+			"0x1234",
+		},
+		{
+			"write and read memory",
+			func(c *client) string {
+				c.request("writeMemory", map[string]interface{}{"memoryReference": "0x0080", "data": base64.StdEncoding.EncodeToString([]byte("HI"))})
+				data := c.request("readMemory", map[string]interface{}{"memoryReference": "0x0080", "count": 2})["data"].(string)
+				bin, _ := base64.StdEncoding.DecodeString(data)
+				return string(bin)
+			},
+			"HI",
+		},
+		{
+			"disassemble around an address",
+			func(c *client) string {
+				var lines []string
+				for _, inst := range c.request("disassemble", map[string]interface{}{
+					"memoryReference":   "0x0006",
+					"instructionOffset": -2,
+					"instructionCount":  4,
+				})["instructions"].([]interface{}) {
+					inst := inst.(map[string]interface{})
+					lines = append(lines, fmt.Sprint(inst["address"], " ", inst["instruction"]))
+				}
+				return strings.Join(lines, "\n")
+			},
+			"0x0000 LXI SP,$0100\n0x0003 CALL $0010\n0x0006 INR B\n0x0007 JMP $0006",
+		},
+		{
+			"pause",
+			func(c *client) string {
+				c.request("setBreakpoints", map[string]interface{}{
+					"source":      map[string]interface{}{"path": "prog.asm"},
+					"breakpoints": []interface{}{},
+				})
+				c.request("setVariable", map[string]interface{}{"variablesReference": registersRef, "name": "PC", "value": "6"})
+				c.request("continue", nil)
+				c.request("pause", map[string]interface{}{"threadId": threadID})
+				return fmt.Sprint(c.event("stopped")["reason"])
+			},
+			"pause",
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			c := startClient(t)
+			c.launch()
+			if got := tC.run(c); got != tC.want {
+				t.Errorf("got %q, want %q", got, tC.want)
+			}
+			c.request("disconnect", nil)
+			c.event("terminated")
+		})
+	}
+}
+
+func TestReadLineMap(t *testing.T) {
+	for _, tC := range []struct {
+		desc string
+		in   string
+		addr uint16
+		want Location
+		err  string
+	}{
+		{"relative file", "; address file:line\n0100 src/prog.asm:3\n", 0x100, Location{"/dir/src/prog.asm", 3}, ""},
+		{"absolute file", "0100 /src/prog.asm:3", 0x100, Location{"/src/prog.asm", 3}, ""},
+		{"spaces in file", "0100 my prog.asm:3", 0x100, Location{"/dir/my prog.asm", 3}, ""},
+		{"missing file", "0100", 0, Location{}, "line 1: want \"address file:line\", got \"0100\""},
+		{"invalid address", "01G0 prog.asm:3", 0, Location{}, "line 1: invalid address \"01G0\""},
+		{"missing line", "0100 prog.asm", 0, Location{}, "line 1: want \"file:line\", got \"prog.asm\""},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			m, err := ReadLineMap(strings.NewReader(tC.in), "/dir")
+			if tC.err != "" {
+				if err == nil || err.Error() != tC.err {
+					t.Fatalf("got error %v, want %q", err, tC.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, ok := m.Location(tC.addr); !ok || got != tC.want {
+				t.Errorf("got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package dap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Location is a line in a source file
+type Location struct {
+	File string
+	Line int
+}
+
+// LineMap maps the addresses of a program to the source lines they were assembled from.
+//
+// Line maps are written by asm8080 -lines, with asm.Program.WriteLineMap. In their textual form, each line holds a
+// hexadecimal address followed by the file and line number of the source it was assembled from, separated by a colon.
+// Blank lines and lines starting with ';' or '#' are ignored:
+//
+//	; address file:line
+//	0100 hello.asm:12
+//	0103 hello.asm:13
+type LineMap struct {
+	byAddr map[uint16]Location
+	byLine map[Location][]uint16
+}
+
+// NewLineMap creates an empty line map
+func NewLineMap() *LineMap {
+	return &LineMap{
+		byAddr: make(map[uint16]Location),
+		byLine: make(map[Location][]uint16),
+	}
+}
+
+// Add records that the code at the given address was assembled from the given source line
+func (m *LineMap) Add(addr uint16, loc Location) {
+	m.byAddr[addr] = loc
+	addrs := append(m.byLine[loc], addr)
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	m.byLine[loc] = addrs
+}
+
+// Location returns the source line the code at the given address was assembled from
+func (m *LineMap) Location(addr uint16) (Location, bool) {
+	loc, ok := m.byAddr[addr]
+	return loc, ok
+}
+
+// Addrs returns the addresses of the code assembled from the given source line, in ascending order. Files are
+// matched by their path, or by their name when no path matches.
+func (m *LineMap) Addrs(file string, line int) []uint16 {
+	file = filepath.Clean(file)
+	if addrs, ok := m.byLine[Location{File: file, Line: line}]; ok {
+		return addrs
+	}
+	for loc, addrs := range m.byLine {
+		if loc.Line == line && filepath.Base(loc.File) == filepath.Base(file) {
+			return addrs
+		}
+	}
+	return nil
+}
+
+// ReadLineMap parses a line map in its textual form. Relative file names are resolved against dir.
+func ReadLineMap(r io.Reader, dir string) (*LineMap, error) {
+	m := NewLineMap()
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		// file names can hold spaces
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 || strings.TrimSpace(fields[1]) == "" {
+			return nil, fmt.Errorf("line %d: want \"address file:line\", got %q", n, line)
+		}
+		fields[1] = strings.TrimSpace(fields[1])
+		addr, err := strconv.ParseUint(fields[0], 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid address %q", n, fields[0])
+		}
+		i := strings.LastIndexByte(fields[1], ':')
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: want \"file:line\", got %q", n, fields[1])
+		}
+		src, err := strconv.Atoi(fields[1][i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid line number %q", n, fields[1][i+1:])
+		}
+
+		file := fields[1][:i]
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		m.Add(uint16(addr), Location{File: filepath.Clean(file), Line: src})
+	}
+	return m, s.Err()
+}
+
+// LoadLineMap reads the line map in the given file
+func LoadLineMap(path string) (*LineMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadLineMap(f, filepath.Dir(path))
+}
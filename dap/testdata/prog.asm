@@ -0,0 +1,8 @@
+	LXI SP,100H	; 0000
+	CALL COUNT	; 0003
+LOOP:	INR B		; 0006
+	JMP LOOP	; 0007
+	ORG 10H
+COUNT:	INR C		; 0010
+	STA 80H		; 0011
+	RET		; 0014
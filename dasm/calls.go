@@ -0,0 +1,208 @@
+package dasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Routine is a subroutine of a program, starting at the target of calls and restarts, or at an entry point
+type Routine struct {
+	// Entry is the address the routine starts at
+	Entry uint16
+	// Start and End delimit the extent of the routine: the addresses from its lowest instruction to the one following
+	// its highest instruction
+	Start, End uint16
+	// Blocks are the basic blocks reached from the entry through jumps and fall-throughs, up to the entries of other
+	// routines, in ascending order of address
+	Blocks []*Block
+	// Exits are the addresses of the instructions control leaves the routine through: returns, PCHL, and jumps or
+	// fall-throughs into other routines or out of the code
+	Exits []uint16
+	// CallSites are the addresses of the calls and restarts to the routine
+	CallSites []uint16
+	// Callers are the entries of the routines calling this one
+	Callers []uint16
+	// Callees are the addresses called by the routine
+	Callees []uint16
+}
+
+// Called tells whether the routine is called directly by a call or restart. Those that aren't are entry points,
+// like the RST vectors used as interrupt handlers, or are only reached through jumps, as jump tables do.
+func (r *Routine) Called() bool {
+	return len(r.CallSites) > 0
+}
+
+// CallGraph holds the routines of a program and the calls between them
+type CallGraph struct {
+	// Routines are the routines of the program, in ascending order of entry
+	Routines []*Routine
+	byEntry  map[uint16]*Routine
+}
+
+// CallGraph splits the graph into routines, starting at the targets of calls and restarts and at the entry points
+// the graph was built from
+func (g *CFG) CallGraph() *CallGraph {
+	cg := &CallGraph{byEntry: make(map[uint16]*Routine)}
+	add := func(entry uint16) *Routine {
+		if r, ok := cg.byEntry[entry]; ok {
+			return r
+		}
+		if _, ok := g.byStart[entry]; !ok {
+			return nil
+		}
+		r := &Routine{Entry: entry}
+		cg.Routines = append(cg.Routines, r)
+		cg.byEntry[entry] = r
+		return r
+	}
+	for _, entry := range g.entries {
+		add(entry)
+	}
+	for _, b := range g.Blocks {
+		for _, e := range b.Edges {
+			if e.Kind != CallEdge {
+				continue
+			}
+			if r := add(e.To); r != nil {
+				r.CallSites = append(r.CallSites, b.Instructions[len(b.Instructions)-1].Addr)
+			}
+		}
+	}
+	sort.Slice(cg.Routines, func(i, j int) bool { return cg.Routines[i].Entry < cg.Routines[j].Entry })
+
+	for _, r := range cg.Routines {
+		g.fillRoutine(r, cg.byEntry)
+	}
+	for _, r := range cg.Routines {
+		for _, callee := range r.Callees {
+			if c, ok := cg.byEntry[callee]; ok {
+				c.Callers = append(c.Callers, r.Entry)
+			}
+		}
+	}
+	return cg
+}
+
+// fillRoutine collects the blocks of the routine, and the exits and callees found in them
+func (g *CFG) fillRoutine(r *Routine, entries map[uint16]*Routine) {
+	visited := make(map[uint16]bool)
+	callees := make(map[uint16]bool)
+	pending := []uint16{r.Entry}
+	for len(pending) > 0 {
+		b := g.byStart[pending[0]]
+		pending = pending[1:]
+		if visited[b.Start] {
+			continue
+		}
+		visited[b.Start] = true
+		r.Blocks = append(r.Blocks, b)
+
+		last := b.Instructions[len(b.Instructions)-1]
+		exit := last.Flow == Return || last.Flow == CondReturn
+		for _, e := range b.Edges {
+			switch {
+			case e.Kind == CallEdge:
+				callees[e.To] = true
+			case e.Kind == Unresolved:
+				exit = true
+			case g.byStart[e.To] == nil || (entries[e.To] != nil && e.To != r.Entry):
+				exit = true
+			default:
+				pending = append(pending, e.To)
+			}
+		}
+		if exit {
+			r.Exits = append(r.Exits, last.Addr)
+		}
+	}
+
+	sort.Slice(r.Blocks, func(i, j int) bool { return r.Blocks[i].Start < r.Blocks[j].Start })
+	sort.Slice(r.Exits, func(i, j int) bool { return r.Exits[i] < r.Exits[j] })
+	r.Start = r.Blocks[0].Start
+	for _, b := range r.Blocks {
+		if b.End() > r.End {
+			r.End = b.End()
+		}
+	}
+	r.Callees = sortedAddrs(callees)
+}
+
+// Routine returns the routine starting at the given address
+func (cg *CallGraph) Routine(entry uint16) (*Routine, bool) {
+	r, ok := cg.byEntry[entry]
+	return r, ok
+}
+
+// WriteText writes a report of the routines: their extent, exits, callers and callees, and whether they're called
+// directly, naming addresses as opts tells
+func (cg *CallGraph) WriteText(w io.Writer, opts Options) error {
+	bw := bufio.NewWriter(w)
+	for _, r := range cg.Routines {
+		fmt.Fprintf(bw, "%s  %04X-%04X", addrName(r.Entry, opts), r.Start, r.End)
+		if !r.Called() {
+			bw.WriteString("  not called directly")
+		}
+		bw.WriteString("\n")
+		writeAddrs(bw, "exits", r.Exits, Options{})
+		writeAddrs(bw, "called from", r.CallSites, Options{})
+		writeAddrs(bw, "callers", r.Callers, opts)
+		writeAddrs(bw, "callees", r.Callees, opts)
+	}
+	return bw.Flush()
+}
+
+// writeAddrs writes an indented line with the given addresses, named as opts tells, unless there are none
+func writeAddrs(w *bufio.Writer, title string, addrs []uint16, opts Options) {
+	if len(addrs) == 0 {
+		return
+	}
+	names := make([]string, len(addrs))
+	for i, addr := range addrs {
+		names[i] = fmt.Sprintf("%04X", addr)
+		if name, ok := opts.label(addr); ok {
+			names[i] = name
+		}
+	}
+	fmt.Fprintf(w, "\t%s: %s\n", title, strings.Join(names, ", "))
+}
+
+// addrName returns the given address, followed by its name if it has one
+func addrName(addr uint16, opts Options) string {
+	if name, ok := opts.label(addr); ok {
+		return fmt.Sprintf("%04X %s", addr, name)
+	}
+	return fmt.Sprintf("%04X", addr)
+}
+
+// WriteDOT writes the call graph in the DOT language of Graphviz, with a node per routine and an edge per caller and
+// callee. Routines not called directly are drawn in red, and addresses called out of the code with dashed nodes.
+func (cg *CallGraph) WriteDOT(w io.Writer, name string, opts Options) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph %s {\n", dotQuote(name))
+	bw.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+
+	external := make(map[uint16]bool)
+	for _, r := range cg.Routines {
+		attrs := ""
+		if !r.Called() {
+			attrs = ", color=red"
+		}
+		fmt.Fprintf(bw, "\tr%04X [label=\"%s\"%s];\n", r.Entry, dotEscape(addrName(r.Entry, opts)), attrs)
+		for _, callee := range r.Callees {
+			if _, ok := cg.byEntry[callee]; !ok {
+				external[callee] = true
+			}
+			fmt.Fprintf(bw, "\tr%04X -> r%04X;\n", r.Entry, callee)
+		}
+	}
+
+	for _, addr := range sortedAddrs(external) {
+		fmt.Fprintf(bw, "\tr%04X [label=\"%s\", style=dashed];\n", addr, dotEscape(addrName(addr, opts)))
+	}
+
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
@@ -0,0 +1,239 @@
+package dasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// EdgeKind tells how control flows along an edge of a control flow graph
+type EdgeKind int
+
+const (
+	// FallThrough edges lead to the instruction following the block
+	FallThrough EdgeKind = iota
+	// Taken edges lead to the target of a jump
+	Taken
+	// CallEdge edges lead to the subroutine called by a call or a restart. Control comes back through the
+	// fall-through edge of the block.
+	CallEdge
+	// Unresolved edges leave the blocks ending with PCHL, whose target is unknown until the code runs. Those
+	// dispatching through a jump table have taken edges to its entries instead.
+	Unresolved
+)
+
+// Edge connects a block with one the control flows to
+type Edge struct {
+	Kind EdgeKind
+	// To is the address control flows to, except for unresolved edges
+	To uint16
+}
+
+// Block is a basic block: a run of instructions only entered through the first one, and only left after the last one
+type Block struct {
+	Start        uint16
+	Instructions []Instruction
+	Edges        []Edge
+}
+
+// End returns the address following the last instruction of the block
+func (b *Block) End() uint16 {
+	last := b.Instructions[len(b.Instructions)-1]
+	return last.Addr + uint16(last.Size)
+}
+
+// CFG is the control flow graph of a program
+type CFG struct {
+	// Blocks are the basic blocks of the program, in ascending order of address
+	Blocks  []*Block
+	byStart map[uint16]*Block
+	// entries are the addresses the control flow was followed from
+	entries []uint16
+}
+
+// BuildCFG follows the control flow of the code in bin, loaded at Options.Origin, from the reset and RST vectors and
+// Options.Entries, and splits the instructions reached into basic blocks. Blocks end at jumps, calls, restarts,
+// returns and PCHL, and right before the targets of jumps, calls and jump tables.
+func BuildCFG(bin []byte, opts Options) (*CFG, error) {
+	m, err := explore(bin, &opts)
+	if err != nil {
+		return nil, err
+	}
+	return m.cfg(), nil
+}
+
+// cfg splits the instructions of the code map into basic blocks
+func (m *codeMap) cfg() *CFG {
+	leaders := make(map[uint16]bool)
+	for _, addr := range m.entries {
+		leaders[addr] = true
+	}
+	for pos, kind := range m.kinds {
+		if kind != opcode {
+			continue
+		}
+		inst := m.instruction(pos)
+		if inst.HasTarget() {
+			leaders[inst.Target] = true
+		}
+		if inst.Flow != Sequential && inst.Flow != Halt {
+			leaders[inst.Addr+uint16(inst.Size)] = true
+		}
+	}
+	for _, targets := range m.dispatch {
+		for _, target := range targets {
+			leaders[target] = true
+		}
+	}
+
+	g := &CFG{byStart: make(map[uint16]*Block), entries: m.entries}
+	var b *Block
+	for pos, kind := range m.kinds {
+		if kind != opcode {
+			continue
+		}
+		inst := m.instruction(pos)
+		if b == nil || leaders[inst.Addr] || b.End() != inst.Addr {
+			b = &Block{Start: inst.Addr}
+			g.Blocks = append(g.Blocks, b)
+			g.byStart[b.Start] = b
+		}
+		b.Instructions = append(b.Instructions, inst)
+	}
+
+	for i, b := range g.Blocks {
+		last := b.Instructions[len(b.Instructions)-1]
+		switch last.Flow {
+		case Jump, CondJump:
+			b.Edges = append(b.Edges, Edge{Kind: Taken, To: last.Target})
+		case Call, CondCall, Restart:
+			b.Edges = append(b.Edges, Edge{Kind: CallEdge, To: last.Target})
+		case Indirect:
+			targets := m.dispatch[last.Addr]
+			if len(targets) == 0 {
+				b.Edges = append(b.Edges, Edge{Kind: Unresolved})
+			}
+			seen := make(map[uint16]bool)
+			for _, target := range targets {
+				if !seen[target] {
+					seen[target] = true
+					b.Edges = append(b.Edges, Edge{Kind: Taken, To: target})
+				}
+			}
+		}
+		// blocks split right before a leader, or before bytes that couldn't be decoded, fall through too
+		next := i+1 < len(g.Blocks) && g.Blocks[i+1].Start == b.End()
+		if last.FallsThrough() && (next || last.Flow != Sequential) {
+			b.Edges = append(b.Edges, Edge{Kind: FallThrough, To: b.End()})
+		}
+	}
+	return g
+}
+
+// Block returns the block starting at the given address
+func (g *CFG) Block(addr uint16) (*Block, bool) {
+	b, ok := g.byStart[addr]
+	return b, ok
+}
+
+// Function returns the graph of the function starting at the given address: the blocks reached from it through
+// jumps and fall-throughs. Calls to other functions are left out.
+func (g *CFG) Function(entry uint16) *CFG {
+	f := &CFG{byStart: make(map[uint16]*Block), entries: []uint16{entry}}
+	pending := []uint16{entry}
+	for len(pending) > 0 {
+		addr := pending[0]
+		pending = pending[1:]
+		b, ok := g.byStart[addr]
+		if !ok || f.byStart[addr] != nil {
+			continue
+		}
+
+		fb := &Block{Start: b.Start, Instructions: b.Instructions}
+		for _, e := range b.Edges {
+			if e.Kind == CallEdge {
+				continue
+			}
+			fb.Edges = append(fb.Edges, e)
+			if e.Kind != Unresolved {
+				pending = append(pending, e.To)
+			}
+		}
+		f.Blocks = append(f.Blocks, fb)
+		f.byStart[addr] = fb
+	}
+	sort.Slice(f.Blocks, func(i, j int) bool { return f.Blocks[i].Start < f.Blocks[j].Start })
+	return f
+}
+
+// edgeStyles are the DOT attributes of each kind of edge
+var edgeStyles = map[EdgeKind]string{
+	FallThrough: "color=black",
+	Taken:       "color=darkgreen",
+	CallEdge:    "color=blue, style=dashed",
+	Unresolved:  "color=red, style=dashed",
+}
+
+// WriteDOT writes the graph in the DOT language of Graphviz. The instructions of the blocks are formatted with the
+// given options. Edges to addresses without a block, out of the code or not decoded, lead to dashed nodes, and
+// unresolved edges to nodes labelled "?".
+func (g *CFG) WriteDOT(w io.Writer, name string, opts Options) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph %s {\n", dotQuote(name))
+	bw.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+
+	external := make(map[uint16]bool)
+	for _, b := range g.Blocks {
+		var label strings.Builder
+		for _, inst := range b.Instructions {
+			if name, ok := opts.label(inst.Addr); ok {
+				label.WriteString(dotEscape(name) + ":\\l")
+			}
+			fmt.Fprintf(&label, "%04X  %s\\l", inst.Addr, dotEscape(Format(inst, opts)))
+		}
+		fmt.Fprintf(bw, "\tb%04X [label=\"%s\"];\n", b.Start, label.String())
+
+		for _, e := range b.Edges {
+			if e.Kind == Unresolved {
+				fmt.Fprintf(bw, "\tu%04X [label=\"?\", shape=circle, color=red];\n", b.Start)
+				fmt.Fprintf(bw, "\tb%04X -> u%04X [%s];\n", b.Start, b.Start, edgeStyles[e.Kind])
+				continue
+			}
+			if _, ok := g.byStart[e.To]; !ok {
+				external[e.To] = true
+			}
+			fmt.Fprintf(bw, "\tb%04X -> b%04X [%s];\n", b.Start, e.To, edgeStyles[e.Kind])
+		}
+	}
+
+	for _, addr := range sortedAddrs(external) {
+		label := fmt.Sprintf("%04X", addr)
+		if name, ok := opts.label(addr); ok {
+			label = dotEscape(name)
+		}
+		fmt.Fprintf(bw, "\tb%04X [label=\"%s\", style=dashed];\n", addr, label)
+	}
+
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// sortedAddrs returns the addresses in the set in ascending order
+func sortedAddrs(set map[uint16]bool) []uint16 {
+	addrs := make([]uint16, 0, len(set))
+	for addr := range set {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	return addrs
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+func dotQuote(s string) string {
+	return `"` + dotEscape(s) + `"`
+}
@@ -2,12 +2,16 @@ package dasm
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"strings"
 	"testing"
 
 	"github.com/miguelff/8080/encoding"
+	"github.com/miguelff/8080/symbols"
 )
 
 func squish(asm string) string {
@@ -42,7 +46,7 @@ func TestDisassemble(t *testing.T) {
 	} {
 		t.Run(tC.desc, func(t *testing.T) {
 			var w strings.Builder
-			err := Disassemble(bytes.NewReader(encoding.HexToBin(tC.code)), &w)
+			err := Disassemble(bytes.NewReader(encoding.MustParseHex(tC.code)), &w)
 			if err != nil {
 				t.Errorf("unexpected error when dissassembling binary: %v", err)
 			}
@@ -55,6 +59,329 @@ func TestDisassemble(t *testing.T) {
 	}
 }
 
+func TestDisassembleWith_Symbols(t *testing.T) {
+	syms := symbols.New()
+	syms.Add("reset", 0x0000)
+	syms.Add("init", 0x18D4)
+	syms.Add("isr", 0x0008)
+
+	var w strings.Builder
+	err := DisassembleWith(bytes.NewReader(encoding.MustParseHex("00 00 00 c3 d4 18 00 00 f5 21 08 00 3e 08")), &w, 0, Options{Symbols: syms})
+	if err != nil {
+		t.Errorf("unexpected error when dissassembling binary: %v", err)
+	}
+
+	want := squish(`reset:
+		NOP
+		NOP
+		NOP
+		JMP init
+		NOP
+		NOP
+		isr:
+		PUSH PSW
+		LXI H,isr
+		MVI A,$08`)
+	if got := w.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDisassembleWith_Flow(t *testing.T) {
+	for _, tC := range []struct {
+		desc string
+		code string
+		opts Options
+		want string
+	}{
+		{
+			"data after jumps and returns",
+			"c3 05 00 41 42 cd 0a 00 e9 ff 3e 01 c9 10 20",
+			Options{Flow: true},
+			`JMP $0005
+				DB $41,$42
+				CALL $000A
+				PCHL
+				DB $FF
+				MVI A,$01
+				RET
+				DB $10,$20`,
+		},
+		{
+			"conditional jumps fall through",
+			"ca 04 00 00 00 d8 76",
+			Options{Flow: true},
+			`JZ $0004
+				NOP
+				NOP
+				RC
+				HLT`,
+		},
+		{
+			"user entry points and origin",
+			"c9 00 00 c9",
+			Options{Flow: true, Origin: 0x100, Entries: []uint16{0x103}},
+			`DB $C9,$00,$00
+				RET`,
+		},
+		{
+			"labelled data is split",
+			"c9 01 02 03",
+			Options{Flow: true, Symbols: table("msg", 0x02)},
+			`RET
+				DB $01
+				msg:
+				DB $02,$03`,
+		},
+		{
+			"overlapping instructions",
+			"c3 04 00 c3 00 00",
+			Options{Flow: true, Entries: []uint16{0x01}},
+			`JMP $0004
+				DB $C3
+				NOP
+				NOP`,
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			var w strings.Builder
+			err := DisassembleWith(bytes.NewReader(encoding.MustParseHex(tC.code)), &w, 0, tC.opts)
+			if err != nil {
+				t.Errorf("unexpected error when dissassembling binary: %v", err)
+			}
+
+			want := squish(tC.want)
+			if got := w.String(); got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestDisassembleWith_Listing(t *testing.T) {
+	for _, tC := range []struct {
+		desc   string
+		code   string
+		offset int
+		opts   Options
+		want   string
+	}{
+		{
+			"addresses follow the offset",
+			"00 00 00 c3 d4 18 3e 08",
+			3,
+			Options{Listing: true},
+			"0003  C3 D4 18  JMP $18D4\n" +
+				"0006  3E 08     MVI A,$08\n",
+		},
+		{
+			"origin, labels and comments",
+			"c3 03 01 c9",
+			0,
+			Options{Listing: true, Origin: 0x100, Symbols: table("done", 0x103), Comments: map[uint16]string{0x100: "skip"}},
+			"0100  C3 03 01  JMP done                ; skip\n" +
+				"                done:\n" +
+				"0103  C9        RET\n",
+		},
+		{
+			"data",
+			"c9 01 02 03 04",
+			0,
+			Options{Listing: true, Flow: true},
+			"0000  C9        RET\n" +
+				"0001  01 02 03  DB $01,$02,$03\n" +
+				"0004  04        DB $04\n",
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			var w strings.Builder
+			err := DisassembleWith(bytes.NewReader(encoding.MustParseHex(tC.code)), &w, tC.offset, tC.opts)
+			if err != nil {
+				t.Errorf("unexpected error when dissassembling binary: %v", err)
+			}
+
+			if got := w.String(); got != tC.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestDisassembleWith_Reassemble(t *testing.T) {
+	var w strings.Builder
+	code := "c3 06 01 01 02 03 cd 00 20 3a 04 01 c2 0d 01 3e ff c9"
+	opts := Options{Reassemble: true, Origin: 0x100, Entries: []uint16{0x100}, Symbols: table("count", 0x104)}
+	err := DisassembleWith(bytes.NewReader(encoding.MustParseHex(code)), &w, 0, opts)
+	if err != nil {
+		t.Errorf("unexpected error when dissassembling binary: %v", err)
+	}
+
+	want := "\tORG 0100H\n" +
+		"L010D\tEQU 010DH\n" +
+		"L2000\tEQU 2000H\n" +
+		"\n" +
+		"\tJMP L0106\n" +
+		"\tDB 01H\n" +
+		"count:\n" +
+		"\tDB 02H,03H\n" +
+		"L0106:\n" +
+		"\tCALL L2000\n" +
+		"\tLDA count\n" +
+		"\tJNZ L010D\n" +
+		"\tMVI A,0FFH\n" +
+		"\tRET\n"
+	if got := w.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDisassembleWith_JumpTables(t *testing.T) {
+	for _, tC := range []struct {
+		desc string
+		code string
+		opts Options
+		want string
+	}{
+		{
+			"reassembled as labels",
+			"21 0d 01 16 00 87 5f 19 5e 23 56 eb e9 11 01 12 01 c9 c9 00 00",
+			Options{Reassemble: true, Origin: 0x100, Entries: []uint16{0x100}},
+			"\tORG 0100H\n" +
+				"\n" +
+				"\tLXI H,T010D\n" +
+				"\tMVI D,00H\n" +
+				"\tADD A\n" +
+				"\tMOV E,A\n" +
+				"\tDAD D\n" +
+				"\tMOV E,M\n" +
+				"\tINX H\n" +
+				"\tMOV D,M\n" +
+				"\tXCHG\n" +
+				"\tPCHL\n" +
+				"T010D:\n" +
+				"\tDW L0111\n" +
+				"\tDW L0112\n" +
+				"L0111:\n" +
+				"\tRET\n" +
+				"L0112:\n" +
+				"\tRET\n" +
+				"\tDB 00H,00H\n",
+		},
+		{
+			"loaded through the accumulator",
+			"21 0a 01 09 7e 23 66 6f e9 ff 0c 01 c9",
+			Options{Flow: true, Origin: 0x100, Entries: []uint16{0x100}},
+			"LXI H,$010A\nDAD B\nMOV A,M\nINX H\nMOV H,M\nMOV L,A\nPCHL\nDB $FF\nDW $010C\nRET\n",
+		},
+		{
+			"HL changed after loading the table",
+			"21 0a 01 23 19 5e 23 56 eb e9 0c 01 c9",
+			Options{Flow: true, Origin: 0x100, Entries: []uint16{0x100}},
+			"LXI H,$010A\nINX H\nDAD D\nMOV E,M\nINX H\nMOV D,M\nXCHG\nPCHL\nDB $0C,$01,$C9\n",
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			var w strings.Builder
+			err := DisassembleWith(bytes.NewReader(encoding.MustParseHex(tC.code)), &w, 0, tC.opts)
+			if err != nil {
+				t.Errorf("unexpected error when dissassembling binary: %v", err)
+			}
+			if got := w.String(); got != tC.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestDisassembleWith_Undefined(t *testing.T) {
+	for _, tC := range []struct {
+		desc    string
+		code    string
+		opts    Options
+		want    string
+		wantErr string
+	}{
+		{
+			"as data",
+			"00 08 cb 00 01 c3 00",
+			Options{},
+			"NOP\n" +
+				"DB $08                  ; undefined\n" +
+				"DB $CB                  ; undefined\n" +
+				"NOP\n" +
+				"LXI B,$00C3\n",
+			"",
+		},
+		{
+			"truncated as data",
+			"00 c3 00",
+			Options{Listing: true},
+			"0000  00        NOP\n" +
+				"0001  C3 00     DB $C3,$00              ; truncated\n",
+			"",
+		},
+		{
+			"as aliases",
+			"08 cb 00 01 d9",
+			Options{Undefined: UndefinedAlias},
+			"NOP                     ; undocumented 08\n" +
+				"JMP $0100               ; undocumented CB\n" +
+				"RET                     ; undocumented D9\n",
+			"",
+		},
+		{
+			"aliases are followed and reassembled as data",
+			"dd 04 00 76 c9",
+			Options{Undefined: UndefinedAlias, Reassemble: true},
+			"\tORG 0000H\n" +
+				"\n" +
+				"\tDB 0DDH,04H,00H         ; undocumented CALL L0004\n" +
+				"\tHLT\n" +
+				"L0004:\n" +
+				"\tRET\n",
+			"",
+		},
+		{
+			"fail",
+			"00 00 00 10",
+			Options{Undefined: UndefinedFail},
+			"",
+			"byte 3 (address 0003): undefined opcode 0x10",
+		},
+		{
+			"fail truncated",
+			"00 cd 00",
+			Options{Undefined: UndefinedFail, Flow: true},
+			"",
+			"byte 1 (address 0001): truncated instruction, CALL needs 3 bytes",
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			var w strings.Builder
+			err := DisassembleWith(bytes.NewReader(encoding.MustParseHex(tC.code)), &w, 0, tC.opts)
+			if tC.wantErr != "" {
+				if err == nil || err.Error() != tC.wantErr {
+					t.Errorf("got error %v, want %q", err, tC.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error when dissassembling binary: %v", err)
+			}
+
+			if got := w.String(); got != tC.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tC.want)
+			}
+		})
+	}
+}
+
+func table(name string, addr uint16) *symbols.Table {
+	t := symbols.New()
+	t.Add(name, addr)
+	return t
+}
+
 func TestDisassembleFirst(t *testing.T) {
 	for _, tC := range []struct {
 		desc string
@@ -73,7 +400,7 @@ func TestDisassembleFirst(t *testing.T) {
 		},
 	} {
 		t.Run(tC.desc, func(t *testing.T) {
-			got, err := DisassembleFirst(encoding.HexToBin(tC.code))
+			got, err := DisassembleFirst(encoding.MustParseHex(tC.code))
 			if err != nil {
 				t.Errorf("unexpected error when dissassembling binary: %v", err)
 			}
@@ -106,3 +433,379 @@ func TestDisassemble_EndToEnd(t *testing.T) {
 		t.Errorf("got %s \n\n want %s", got, want)
 	}
 }
+
+func TestDecode(t *testing.T) {
+	for _, tC := range []struct {
+		desc    string
+		code    string
+		addr    uint16
+		want    Instruction
+		wantErr error
+	}{
+		{
+			"register operands",
+			"41",
+			0x10,
+			Instruction{Addr: 0x10, Opcode: 0x41, Size: 1, Mnemonic: "MOV", Regs: "B,C"},
+			nil,
+		},
+		{
+			"immediate 16-bit value",
+			"31 00 24",
+			0,
+			Instruction{Opcode: 0x31, Size: 3, Mnemonic: "LXI", Regs: "SP", Operand: Immediate16, Value: 0x2400},
+			nil,
+		},
+		{
+			"port",
+			"db 01",
+			0,
+			Instruction{Opcode: 0xDB, Size: 2, Mnemonic: "IN", Operand: Port, Value: 0x01},
+			nil,
+		},
+		{
+			"conditional call",
+			"c4 cd 17",
+			0x100,
+			Instruction{Addr: 0x100, Opcode: 0xC4, Size: 3, Mnemonic: "CNZ", Operand: Address, Value: 0x17CD, Flow: CondCall, Target: 0x17CD},
+			nil,
+		},
+		{
+			"restart",
+			"cf",
+			0,
+			Instruction{Opcode: 0xCF, Size: 1, Mnemonic: "RST", Operand: Vector, Value: 1, Flow: Restart, Target: 0x08},
+			nil,
+		},
+		{
+			"undefined",
+			"08",
+			0x20,
+			Instruction{Addr: 0x20, Opcode: 0x08, Size: 1},
+			ErrUndefined,
+		},
+		{
+			"truncated",
+			"c3 d4",
+			0,
+			Instruction{Opcode: 0xC3, Size: 1},
+			ErrTruncated,
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			got, err := Decode(encoding.MustParseHex(tC.code), tC.addr)
+			if !errors.Is(err, tC.wantErr) {
+				t.Errorf("got error %v, want %v", err, tC.wantErr)
+			}
+			if got != tC.want {
+				t.Errorf("got %+v, want %+v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	d := NewDecoder(bytes.NewReader(encoding.MustParseHex("00 c3 d4 18 08 3e")), 0x100)
+
+	var got []string
+	for {
+		inst, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			got = append(got, fmt.Sprintf("%04X error", inst.Addr))
+			continue
+		}
+		got = append(got, fmt.Sprintf("%04X %s", inst.Addr, inst))
+	}
+
+	want := []string{"0100 NOP", "0101 JMP $18D4", "0104 error", "0105 error"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormat_Zilog(t *testing.T) {
+	for _, tC := range []struct {
+		code string
+		want string
+	}{
+		{"7e", "LD A,(HL)"},
+		{"41", "LD B,C"},
+		{"36 ff", "LD (HL),0FFh"},
+		{"21 00 24", "LD HL,2400h"},
+		{"3a 34 12", "LD A,(1234h)"},
+		{"22 34 12", "LD (1234h),HL"},
+		{"12", "LD (DE),A"},
+		{"c2 34 12", "JP NZ,1234h"},
+		{"f4 34 12", "CALL P,1234h"},
+		{"e8", "RET PE"},
+		{"cd 34 12", "CALL 1234h"},
+		{"c9", "RET"},
+		{"e9", "JP (HL)"},
+		{"eb", "EX DE,HL"},
+		{"f5", "PUSH AF"},
+		{"39", "ADD HL,SP"},
+		{"9e", "SBC A,(HL)"},
+		{"fe 10", "CP 10h"},
+		{"ff", "RST 38h"},
+		{"db 01", "IN A,(01h)"},
+		{"d3 06", "OUT (06h),A"},
+		{"76", "HALT"},
+		{"00", "NOP"},
+	} {
+		t.Run(tC.want, func(t *testing.T) {
+			inst, err := Decode(encoding.MustParseHex(tC.code), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Format(inst, Options{Syntax: Zilog}); got != tC.want {
+				t.Errorf("got %q, want %q", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestFormat_ZilogCoversAllOpcodes(t *testing.T) {
+	z80 := make(map[string]bool)
+	for _, m := range strings.Fields("LD EX JP ADD ADC SUB SBC AND XOR OR CP INC DEC RLCA RRCA RLA RRA CPL SCF CCF HALT " +
+		"RST PUSH POP IN OUT CALL RET NOP DAA DI EI") {
+		z80[m] = true
+	}
+
+	for op := 0; op < 256; op++ {
+		inst, err := Decode([]byte{byte(op), 0x34, 0x12}, 0)
+		if errors.Is(err, ErrUndefined) {
+			continue
+		}
+		got := Format(inst, Options{Syntax: Zilog})
+		if strings.Contains(got, "{") || strings.Contains(got, ",M") || !z80[strings.Fields(got)[0]] {
+			t.Errorf("%02X: %q has no Z80 equivalent", op, got)
+		}
+	}
+}
+
+func TestBuildCFG(t *testing.T) {
+	code := encoding.MustParseHex(`
+		0000: 3e 01     ; MVI A,1
+		0002: ca 09 00  ; JZ 0009
+		0005: cd 0c 00  ; CALL 000C
+		0008: 76        ; HLT
+		0009: e9        ; PCHL
+		000A: 00 00     ; data
+		000C: c9        ; RET
+	`)
+	kinds := map[EdgeKind]string{FallThrough: "fall", Taken: "taken", CallEdge: "call", Unresolved: "unresolved"}
+
+	g, err := BuildCFG(code, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0100 LXI H,0109; DAD D; MOV E,M; INX H; MOV D,M; XCHG; PCHL / 0109 DW 010D, 010E / 010D RET / 010E RET
+	table, err := BuildCFG(encoding.MustParseHex("21 09 01 19 5e 23 56 eb e9 0d 01 0e 01 c9 c9"), Options{Origin: 0x100, Entries: []uint16{0x100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tC := range []struct {
+		desc  string
+		graph *CFG
+		want  []string
+	}{
+		{
+			"program",
+			g,
+			[]string{
+				"0000-0005 taken:0009 fall:0005",
+				"0005-0008 call:000C fall:0008",
+				"0008-0009 fall:0009",
+				"0009-000A unresolved:0000",
+				"000C-000D",
+			},
+		},
+		{
+			"function",
+			g.Function(0),
+			[]string{
+				"0000-0005 taken:0009 fall:0005",
+				"0005-0008 fall:0008",
+				"0008-0009 fall:0009",
+				"0009-000A unresolved:0000",
+			},
+		},
+		{
+			"jump table",
+			table,
+			[]string{
+				"0100-0109 taken:010D taken:010E",
+				"010D-010E",
+				"010E-010F",
+			},
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			var got []string
+			for _, b := range tC.graph.Blocks {
+				s := fmt.Sprintf("%04X-%04X", b.Start, b.End())
+				for _, e := range b.Edges {
+					s += fmt.Sprintf(" %s:%04X", kinds[e.Kind], e.To)
+				}
+				got = append(got, s)
+			}
+			if strings.Join(got, "\n") != strings.Join(tC.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tC.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestCFG_WriteDOT(t *testing.T) {
+	g, err := BuildCFG(encoding.MustParseHex("c2 06 00 c3 00 10 e9"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w strings.Builder
+	if err := g.WriteDOT(&w, "main", Options{Symbols: table("far\"away", 0x1000)}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := w.String()
+	for _, want := range []string{
+		"digraph \"main\" {\n",
+		"\tb0000 [label=\"0000  JNZ $0006\\l\"];\n",
+		"\tb0000 -> b0006 [color=darkgreen];\n",
+		"\tb0000 -> b0003 [color=black];\n",
+		"\tb0003 [label=\"0003  JMP far\\\"away\\l\"];\n",
+		"\tb0006 -> u0006 [color=red, style=dashed];\n",
+		"\tb1000 [label=\"far\\\"away\", style=dashed];\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestCallGraph(t *testing.T) {
+	code := encoding.MustParseHex(`
+		0100: cd 09 01  ; CALL 0109
+		0103: cd 0e 01  ; CALL 010E
+		0106: c3 0e 01  ; JMP 010E
+		0109: c8        ; RZ
+		010A: cd 0e 01  ; CALL 010E
+		010D: c9        ; RET
+		010E: e9        ; PCHL
+	`)
+	g, err := BuildCFG(code, Options{Origin: 0x100, Entries: []uint16{0x100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cg := g.CallGraph()
+	opts := Options{Symbols: table("sub", 0x10E)}
+
+	for _, tC := range []struct {
+		desc  string
+		write func(w io.Writer) error
+		want  string
+	}{
+		{
+			"text",
+			func(w io.Writer) error { return cg.WriteText(w, opts) },
+			`0100  0100-0109  not called directly
+				exits: 0106
+				callees: 0109, sub
+				0109  0109-010E
+				exits: 0109, 010D
+				called from: 0100
+				callers: 0100
+				callees: sub
+				010E sub  010E-010F
+				exits: 010E
+				called from: 0103, 010A
+				callers: 0100, 0109`,
+		},
+		{
+			"dot",
+			func(w io.Writer) error { return cg.WriteDOT(w, "calls", opts) },
+			`digraph "calls" {
+				node [shape=box, fontname="monospace"];
+				r0100 [label="0100", color=red];
+				r0100 -> r0109;
+				r0100 -> r010E;
+				r0109 [label="0109"];
+				r0109 -> r010E;
+				r010E [label="010E sub"];
+				}`,
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			var w strings.Builder
+			if err := tC.write(&w); err != nil {
+				t.Fatal(err)
+			}
+			got := squish(strings.TrimSuffix(w.String(), "\n"))
+			if want := squish(tC.want); got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestXrefs(t *testing.T) {
+	code := encoding.MustParseHex(`
+		0000: 3a 72 20  ; LDA 2072
+		0003: 32 72 20  ; STA 2072
+		0006: 21 0d 00  ; LXI H,000D
+		0009: db 01     ; IN 01
+		000B: d3 01     ; OUT 01
+		000D: c3 00 00  ; JMP 0000
+	`)
+	g, err := BuildCFG(code, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := g.Xrefs()
+
+	for _, tC := range []struct {
+		desc  string
+		write func(w io.Writer) error
+		want  string
+	}{
+		{
+			"report",
+			func(w io.Writer) error { return x.WriteText(w, Options{Symbols: table("score", 0x2072)}) },
+			`0000
+				000D  exec     JMP $0000
+				000D
+				0006  pointer  LXI H,$000D
+				2072 score
+				0000  read     LDA score
+				0003  write    STA score
+				port 01
+				0009  read     IN $01
+				000B  write    OUT $01`,
+		},
+		{
+			"inline",
+			func(w io.Writer) error {
+				return DisassembleWith(bytes.NewReader(code), w, 0, Options{Flow: true, Comments: x.Comments()})
+			},
+			`LDA $2072               ; 2072 read; xref 000D exec
+				STA $2072               ; 2072 write
+				LXI H,$000D             ; 000D pointer
+				IN $01
+				OUT $01
+				JMP $0000               ; xref 0006 pointer`,
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			var w strings.Builder
+			if err := tC.write(&w); err != nil {
+				t.Fatal(err)
+			}
+			got := squish(strings.TrimSuffix(w.String(), "\n"))
+			if want := squish(tC.want); got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package dasm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/miguelff/8080/isa"
+)
+
+var (
+	// ErrUndefined is returned when decoding an opcode with no instruction assigned
+	ErrUndefined = errors.New("undefined opcode")
+	// ErrTruncated is returned when the code ends before the instruction being decoded does
+	ErrTruncated = errors.New("truncated instruction")
+)
+
+// OperandKind tells what the operand following the opcode of an instruction is
+type OperandKind = isa.Operand
+
+// The kinds of operands, as described by package isa
+const (
+	NoOperand   = isa.NoOperand
+	Immediate8  = isa.Immediate8
+	Port        = isa.Port
+	Immediate16 = isa.Immediate16
+	Address     = isa.Address
+	Vector      = isa.Vector
+)
+
+// Flow tells how an instruction transfers control
+type Flow int
+
+const (
+	// Sequential instructions are followed by the next one
+	Sequential Flow = iota
+	// Jump transfers control to the target: JMP
+	Jump
+	// CondJump transfers control to the target when a condition holds: JNZ, JZ, JNC...
+	CondJump
+	// Call calls the subroutine at the target: CALL
+	Call
+	// CondCall calls the subroutine at the target when a condition holds: CNZ, CZ, CNC...
+	CondCall
+	// Restart calls the subroutine at the restart vector: RST
+	Restart
+	// Return returns from a subroutine: RET
+	Return
+	// CondReturn returns from a subroutine when a condition holds: RNZ, RZ, RNC...
+	CondReturn
+	// Indirect jumps to the address in HL, unknown until the code runs: PCHL
+	Indirect
+	// Halt stops the processor until an interrupt arrives: HLT
+	Halt
+)
+
+// Instruction is a decoded machine instruction
+type Instruction struct {
+	// Addr is the address of the instruction
+	Addr uint16
+	// Opcode is the first byte of the instruction
+	Opcode byte
+	// Size is the number of bytes of the instruction, including its opcode
+	Size int
+	// Mnemonic is the name of the instruction, like "MOV"
+	Mnemonic string
+	// Regs are the register operands, like "B,C" for MOV B,C, or "SP" for LXI SP,D16
+	Regs string
+	// Operand is the kind of the operand following the opcode, or encoded in it for RST
+	Operand OperandKind
+	// Value is the value of the operand
+	Value uint16
+	// Flow tells how the instruction transfers control
+	Flow Flow
+	// Target is the address control can be transferred to by jumps, calls and restarts
+	Target uint16
+	// Undocumented is set on the instructions decoded from undefined opcodes by DecodeUndocumented
+	Undocumented bool
+}
+
+// HasTarget tells whether Target holds the address the instruction can transfer control to
+func (i Instruction) HasTarget() bool {
+	switch i.Flow {
+	case Jump, CondJump, Call, CondCall, Restart:
+		return true
+	}
+	return false
+}
+
+// FallsThrough tells whether the next instruction can be executed after this one
+func (i Instruction) FallsThrough() bool {
+	switch i.Flow {
+	case Jump, Return, Indirect:
+		return false
+	}
+	return true
+}
+
+// String formats the instruction with the default options, like "JMP $18D4"
+func (i Instruction) String() string {
+	return Format(i, Options{})
+}
+
+// aliases are the documented opcodes that the undefined opcodes behave like on the 8080
+var aliases = map[byte]byte{
+	0x08: 0x00, 0x10: 0x00, 0x18: 0x00, 0x20: 0x00, 0x28: 0x00, 0x30: 0x00, 0x38: 0x00, // NOP
+	0xCB: 0xC3,                         // JMP
+	0xD9: 0xC9,                         // RET
+	0xDD: 0xCD, 0xED: 0xCD, 0xFD: 0xCD, // CALL
+}
+
+// Decode decodes the instruction at the start of bin, which is found at the given address. Undefined opcodes are
+// reported with ErrUndefined, and instructions longer than bin with ErrTruncated, along with an instruction holding
+// the opcode and a size of 1.
+func Decode(bin []byte, addr uint16) (Instruction, error) {
+	if len(bin) == 0 {
+		return Instruction{}, io.EOF
+	}
+
+	op := bin[0]
+	inst := Instruction{Addr: addr, Opcode: op, Size: 1}
+	def := isa.Opcodes[op]
+	if !def.Defined() {
+		return inst, fmt.Errorf("%w 0x%02X", ErrUndefined, op)
+	}
+	if len(bin) < def.Size {
+		return inst, fmt.Errorf("%w, %s needs %d bytes", ErrTruncated, def.Mnemonic, def.Size)
+	}
+
+	inst.Size = def.Size
+	inst.Mnemonic = def.Mnemonic
+	inst.Regs = def.Regs
+	inst.Operand = def.Operand
+	switch def.Size {
+	case 2:
+		inst.Value = uint16(bin[1])
+	case 3:
+		inst.Value = uint16(bin[2])<<8 | uint16(bin[1])
+	}
+	if def.Operand == Vector {
+		inst.Value = uint16(def.Vector)
+	}
+
+	inst.Flow = flowOf(op)
+	switch inst.Flow {
+	case Jump, CondJump, Call, CondCall:
+		inst.Target = inst.Value
+	case Restart:
+		inst.Target = inst.Value * 8
+	}
+	return inst, nil
+}
+
+// DecodeUndocumented works like Decode, but decodes the undefined opcodes as the documented instructions they behave
+// like on the 8080, marking them as Undocumented: 0x08, 0x10, 0x18, 0x20, 0x28, 0x30 and 0x38 as NOP, 0xCB as JMP,
+// 0xD9 as RET, and 0xDD, 0xED and 0xFD as CALL.
+func DecodeUndocumented(bin []byte, addr uint16) (Instruction, error) {
+	if len(bin) == 0 {
+		return Decode(bin, addr)
+	}
+	alias, ok := aliases[bin[0]]
+	if !ok {
+		return Decode(bin, addr)
+	}
+
+	documented := append([]byte{alias}, bin[1:]...)
+	inst, err := Decode(documented, addr)
+	inst.Opcode = bin[0]
+	inst.Undocumented = err == nil
+	return inst, err
+}
+
+// flowOf tells how the instruction with the given opcode transfers control
+func flowOf(op byte) Flow {
+	switch {
+	case op == 0xC3:
+		return Jump
+	case op == 0xCD:
+		return Call
+	case op == 0xC9:
+		return Return
+	case op == 0xE9:
+		return Indirect
+	case op == 0x76:
+		return Halt
+	case op&0xC7 == 0xC2:
+		return CondJump
+	case op&0xC7 == 0xC4:
+		return CondCall
+	case op&0xC7 == 0xC0:
+		return CondReturn
+	case op&0xC7 == 0xC7:
+		return Restart
+	}
+	return Sequential
+}
+
+// Decoder decodes the instructions of the machine code read from an io.ReaderAt
+type Decoder struct {
+	r      io.ReaderAt
+	origin uint16
+	next   uint16
+}
+
+// NewDecoder creates a decoder of the code in r, whose first byte is found at the given origin address. Decoding
+// starts at the origin.
+func NewDecoder(r io.ReaderAt, origin uint16) *Decoder {
+	return &Decoder{r: r, origin: origin, next: origin}
+}
+
+// Decode decodes the instruction at the given address. Subsequent calls to Next continue after it, even when it
+// can't be decoded.
+func (d *Decoder) Decode(addr uint16) (Instruction, error) {
+	buf := make([]byte, 3)
+	n, err := d.r.ReadAt(buf, int64(addr)-int64(d.origin))
+	if n == 0 {
+		if err == nil || err == io.EOF {
+			err = io.EOF
+		}
+		return Instruction{}, err
+	}
+	if err != nil && err != io.EOF {
+		return Instruction{}, err
+	}
+
+	inst, err := Decode(buf[:n], addr)
+	d.next = addr + uint16(inst.Size)
+	return inst, err
+}
+
+// Next decodes the instruction following the last one decoded. It returns io.EOF at the end of the code.
+func (d *Decoder) Next() (Instruction, error) {
+	return d.Decode(d.next)
+}
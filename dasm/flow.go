@@ -0,0 +1,261 @@
+package dasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/miguelff/8080/symbols"
+)
+
+// byteKind tells what a byte of a program was found to be by following its control flow
+type byteKind byte
+
+const (
+	// data bytes were never reached as part of an instruction
+	data byteKind = iota
+	// opcode bytes start an instruction
+	opcode
+	// operand bytes follow an opcode, or the first byte of a pointer
+	operand
+	// pointer bytes start a 16-bit entry of a jump table
+	pointer
+)
+
+// rstVectors are the addresses the RST instructions jump to. RST 0 is also the reset vector.
+var rstVectors = []uint16{0x00, 0x08, 0x10, 0x18, 0x20, 0x28, 0x30, 0x38}
+
+// codeMap classifies the bytes of a program loaded at origin into instructions and data
+type codeMap struct {
+	bin    []byte
+	origin uint16
+	kinds  []byteKind
+	decode func([]byte, uint16) (Instruction, error)
+	// entries are the addresses exploration started from
+	entries []uint16
+	// tables are the targets of the jump tables found, by the address of the table
+	tables map[uint16][]uint16
+	// dispatch are the targets of the jump tables found, by the address of the PCHL dispatching through them
+	dispatch map[uint16][]uint16
+}
+
+// explore follows the control flow of the program from the reset and RST vectors, and then from the entry points in
+// opts, through jumps, calls and returns, and returns the resulting map of its code. Paths end at unconditional jumps
+// and returns, at PCHL, whose target is unknown, and at instructions overlapping others. Undefined opcodes end paths
+// too, unless opts tells to decode them as their aliases, or to fail. Once no more code is reached, the jump tables
+// dispatched through with PCHL are read, and their entries followed in turn.
+func explore(bin []byte, opts *Options) (*codeMap, error) {
+	m := &codeMap{
+		bin:      bin,
+		origin:   opts.Origin,
+		kinds:    make([]byteKind, len(bin)),
+		decode:   opts.decode,
+		tables:   make(map[uint16][]uint16),
+		dispatch: make(map[uint16][]uint16),
+	}
+	m.entries = append(append([]uint16(nil), rstVectors...), opts.Entries...)
+
+	pending := append([]uint16(nil), m.entries...)
+	for len(pending) > 0 {
+		if err := m.follow(pending, opts); err != nil {
+			return nil, err
+		}
+		pending = m.jumpTables(opts)
+	}
+	return m, nil
+}
+
+// follow classifies the instructions reached from the given addresses
+func (m *codeMap) follow(pending []uint16, opts *Options) error {
+	for len(pending) > 0 {
+		addr := pending[0]
+		pending = pending[1:]
+
+		for {
+			pos, ok := m.pos(addr)
+			if !ok || m.kinds[pos] != data {
+				break
+			}
+			inst, err := m.decode(m.bin[pos:], addr)
+			if err != nil && opts.Undefined == UndefinedFail {
+				return fmt.Errorf("byte %d (address %04X): %w", pos, addr, err)
+			}
+			if err != nil || !m.free(pos+1, pos+inst.Size) {
+				break
+			}
+
+			m.kinds[pos] = opcode
+			for i := pos + 1; i < pos+inst.Size; i++ {
+				m.kinds[i] = operand
+			}
+			if inst.HasTarget() {
+				pending = append(pending, inst.Target)
+			}
+			if !inst.FallsThrough() {
+				break
+			}
+			addr += uint16(inst.Size)
+		}
+	}
+	return nil
+}
+
+// pos returns the position in the program of the given address
+func (m *codeMap) pos(addr uint16) (int, bool) {
+	pos := int(addr) - int(m.origin)
+	return pos, pos >= 0 && pos < len(m.bin)
+}
+
+// free tells whether the bytes in [from, to) haven't been classified as part of an instruction yet
+func (m *codeMap) free(from, to int) bool {
+	for i := from; i < to; i++ {
+		if m.kinds[i] != data {
+			return false
+		}
+	}
+	return true
+}
+
+// instruction returns the instruction at the given position, which must be classified as an opcode
+func (m *codeMap) instruction(pos int) Instruction {
+	inst, _ := m.decode(m.bin[pos:], m.origin+uint16(pos))
+	return inst
+}
+
+// dataPerLine is the maximum number of bytes written in each DB directive. Listings hold as many as instructions do.
+const (
+	dataPerLine        = 8
+	dataPerListingLine = 3
+)
+
+// disassembleFlow disassembles the code reached from the entry points in opts, starting at the given offset, and
+// writes the entries of the jump tables found as DW directives, and the rest of the bytes as DB directives
+func disassembleFlow(r io.Reader, w *bufio.Writer, offset int, opts *Options) error {
+	bin, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if offset > len(bin) {
+		return fmt.Errorf("offset %d is past the end of the code (%d bytes)", offset, len(bin))
+	}
+
+	m, err := explore(bin, opts)
+	if err != nil {
+		return err
+	}
+	if opts.Reassemble {
+		opts.labels, opts.tables = m.branchLabels()
+		if err := m.writeHeader(w, offset, opts); err != nil {
+			return err
+		}
+	}
+	perLine := dataPerLine
+	if opts.Listing {
+		perLine = dataPerListingLine
+	}
+
+	for pos := offset; pos < len(bin); {
+		if m.kinds[pos] == opcode {
+			size, err := writeInstruction(w, pos, bin[pos:pos+m.instruction(pos).Size], opts)
+			if err != nil {
+				return err
+			}
+			pos += size
+			continue
+		}
+		if m.kinds[pos] == pointer {
+			text := "DW " + opts.address(m.pointer(pos), true)
+			if err := writeLine(w, opts.Origin+uint16(pos), bin[pos:pos+2], text, "", opts); err != nil {
+				return err
+			}
+			pos += 2
+			continue
+		}
+
+		end := pos + 1
+		for end < len(bin) && end-pos < perLine && m.kinds[end] != opcode && m.kinds[end] != pointer {
+			if _, ok := opts.label(opts.Origin + uint16(end)); ok {
+				break
+			}
+			end++
+		}
+		if err := writeLine(w, opts.Origin+uint16(pos), bin[pos:end], opts.data(bin[pos:end]), "", opts); err != nil {
+			return err
+		}
+		pos = end
+	}
+	return nil
+}
+
+// pointer returns the target of the jump table entry at the given position, which must be classified as a pointer
+func (m *codeMap) pointer(pos int) uint16 {
+	return uint16(m.bin[pos+1])<<8 | uint16(m.bin[pos])
+}
+
+// branchLabels names the targets of the jumps, calls and jump table entries in the code after their addresses, like
+// L18D4, and the jump tables like T1A00
+func (m *codeMap) branchLabels() (labels, tables *symbols.Table) {
+	labels, tables = symbols.New(), symbols.New()
+	for pos, kind := range m.kinds {
+		switch kind {
+		case opcode:
+			if inst := m.instruction(pos); inst.HasTarget() && inst.Operand == Address {
+				labels.Add(fmt.Sprintf("L%04X", inst.Target), inst.Target)
+			}
+		case pointer:
+			target := m.pointer(pos)
+			labels.Add(fmt.Sprintf("L%04X", target), target)
+		}
+	}
+	for table := range m.tables {
+		tables.Add(fmt.Sprintf("T%04X", table), table)
+	}
+	return labels, tables
+}
+
+// writeHeader writes the ORG directive of the code written from the given offset, and defines with EQU the names
+// used as operands that won't be defined as labels, because they fall out of the code or inside an instruction.
+func (m *codeMap) writeHeader(w *bufio.Writer, offset int, opts *Options) error {
+	if _, err := fmt.Fprintf(w, "\tORG %s\n", opts.hex(opts.Origin+uint16(offset), 4)); err != nil {
+		return err
+	}
+
+	defined := make(map[string]bool)
+	var undefined []uint16
+	for pos := offset; pos < len(m.bin); pos++ {
+		var addr uint16
+		var branch bool
+		switch m.kinds[pos] {
+		case opcode:
+			inst := m.instruction(pos)
+			if inst.Operand != Address && inst.Operand != Immediate16 {
+				continue
+			}
+			addr, branch = inst.Value, inst.HasTarget()
+		case pointer:
+			addr, branch = m.pointer(pos), true
+		default:
+			continue
+		}
+		name, ok := opts.name(addr, branch)
+		if !ok || defined[name] {
+			continue
+		}
+		defined[name] = true
+		if at, ok := m.pos(addr); !ok || at < offset || m.kinds[at] == operand {
+			undefined = append(undefined, addr)
+		}
+	}
+
+	sort.Slice(undefined, func(i, j int) bool { return undefined[i] < undefined[j] })
+	for _, addr := range undefined {
+		name, _ := opts.name(addr, true)
+		if _, err := fmt.Fprintf(w, "%s\tEQU %s\n", name, opts.hex(addr, 4)); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteString("\n")
+	return err
+}
@@ -0,0 +1,133 @@
+package dasm
+
+// dispatchTails are the instruction sequences loading HL with the entry of a jump table HL points to, and jumping to
+// it. They follow the DAD adding the index of the entry to the address of the table.
+var dispatchTails = [][]string{
+	{"MOV E,M", "INX H", "MOV D,M", "XCHG", "PCHL"},
+	{"MOV A,M", "INX H", "MOV H,M", "MOV L,A", "PCHL"},
+}
+
+// maxIndexing is the number of instructions computing the index of the entry allowed between LXI H,table and DAD
+const maxIndexing = 4
+
+// jumpTables finds the jump tables dispatched through by the PCHL instructions reached, following the idiom
+//
+//	LXI H,table; ...; DAD D; MOV E,M; INX H; MOV D,M; XCHG; PCHL
+//
+// or its variants, reads their entries, and returns those not reached yet
+func (m *codeMap) jumpTables(opts *Options) []uint16 {
+	var pending []uint16
+	for pos, kind := range m.kinds {
+		if kind != opcode || m.bin[pos] != 0xE9 {
+			continue
+		}
+		addr := m.origin + uint16(pos)
+		if _, ok := m.dispatch[addr]; ok {
+			continue
+		}
+		table, ok := m.jumpTable(pos)
+		if !ok {
+			continue
+		}
+
+		targets, ok := m.tables[table]
+		if !ok {
+			targets = m.readTable(table, opts)
+			m.tables[table] = targets
+			pending = append(pending, targets...)
+		}
+		m.dispatch[addr] = targets
+	}
+	return pending
+}
+
+// jumpTable returns the address of the jump table the PCHL at the given position dispatches through, if the
+// instructions before it follow one of the idioms
+func (m *codeMap) jumpTable(pos int) (uint16, bool) {
+	insts := m.preceding(pos, len(dispatchTails[0])+2+maxIndexing)
+tails:
+	for _, tail := range dispatchTails {
+		if len(insts) < len(tail)+2 {
+			continue
+		}
+		for i, text := range tail {
+			if Format(insts[len(tail)-1-i], Options{}) != text {
+				continue tails
+			}
+		}
+		if dad := insts[len(tail)]; dad.Mnemonic != "DAD" || (dad.Regs != "B" && dad.Regs != "D") {
+			continue
+		}
+		for _, inst := range insts[len(tail)+1:] {
+			if inst.Mnemonic == "LXI" && inst.Regs == "H" {
+				return inst.Value, true
+			}
+			if inst.Flow != Sequential || writesHL(inst) {
+				break
+			}
+		}
+	}
+	return 0, false
+}
+
+// preceding returns up to n instructions ending at the one at the given position, walking back through the ones
+// right before it, in reverse order
+func (m *codeMap) preceding(pos, n int) []Instruction {
+	insts := []Instruction{m.instruction(pos)}
+	for len(insts) < n {
+		prev := -1
+		for size := 1; size <= 3 && pos-size >= 0; size++ {
+			if m.kinds[pos-size] == opcode && m.instruction(pos-size).Size == size {
+				prev = pos - size
+				break
+			}
+		}
+		if prev < 0 {
+			break
+		}
+		pos = prev
+		insts = append(insts, m.instruction(pos))
+	}
+	return insts
+}
+
+// writesHL tells whether the instruction changes the contents of H or L
+func writesHL(inst Instruction) bool {
+	switch inst.Mnemonic {
+	case "DAD", "XCHG", "XTHL", "LHLD":
+		return true
+	case "LXI", "INX", "DCX", "POP", "MOV", "MVI", "INR", "DCR":
+		return inst.Regs[0] == 'H' || inst.Regs[0] == 'L'
+	}
+	return false
+}
+
+// readTable reads the entries of the jump table at the given address, classifying them as pointers, and returns
+// their targets. The table ends before the first entry that isn't free, that's labelled, or whose target is out of
+// the code, inside an instruction or the table, or can't be decoded.
+func (m *codeMap) readTable(table uint16, opts *Options) []uint16 {
+	start, _ := m.pos(table)
+	var targets []uint16
+	for addr := table; ; addr += 2 {
+		pos, ok := m.pos(addr)
+		if !ok || pos+2 > len(m.bin) || !m.free(pos, pos+2) {
+			break
+		}
+		if _, ok := opts.Symbols.Name(addr); ok && addr != table {
+			break
+		}
+		target := m.pointer(pos)
+		at, ok := m.pos(target)
+		if !ok || m.kinds[at] == operand || m.kinds[at] == pointer || (at >= start && at < pos+2) {
+			break
+		}
+		if _, err := m.decode(m.bin[at:], target); err != nil {
+			break
+		}
+
+		m.kinds[pos] = pointer
+		m.kinds[pos+1] = operand
+		targets = append(targets, target)
+	}
+	return targets
+}
@@ -0,0 +1,130 @@
+package dasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Access tells how an instruction refers to an address or port
+type Access int
+
+const (
+	// Read accesses load the contents of an address: LDA and LHLD, or read a port: IN
+	Read Access = iota
+	// Write accesses store into an address: STA and SHLD, or write a port: OUT
+	Write
+	// Exec accesses transfer control to an address: jumps, calls and restarts
+	Exec
+	// PointerLoad accesses load an address into a register pair: LXI
+	PointerLoad
+)
+
+var accessNames = []string{"read", "write", "exec", "pointer"}
+
+func (a Access) String() string {
+	return accessNames[a]
+}
+
+// Ref is a reference to an address or port made by an instruction
+type Ref struct {
+	Inst   Instruction
+	Access Access
+}
+
+// Xrefs are the cross references of a program: the instructions referring to each address and port
+type Xrefs struct {
+	// Memory maps addresses to the instructions referring to them. The references to each address are sorted by the
+	// address of their instructions.
+	Memory map[uint16][]Ref
+	// Ports maps port numbers to the IN and OUT instructions reading and writing them, sorted the same way
+	Ports map[byte][]Ref
+}
+
+// Xrefs collects the references to addresses and ports made by the instructions in the graph
+func (g *CFG) Xrefs() *Xrefs {
+	x := &Xrefs{Memory: make(map[uint16][]Ref), Ports: make(map[byte][]Ref)}
+	for _, b := range g.Blocks {
+		for _, inst := range b.Instructions {
+			switch {
+			case inst.HasTarget():
+				x.Memory[inst.Target] = append(x.Memory[inst.Target], Ref{inst, Exec})
+			case inst.Mnemonic == "LDA" || inst.Mnemonic == "LHLD":
+				x.Memory[inst.Value] = append(x.Memory[inst.Value], Ref{inst, Read})
+			case inst.Mnemonic == "STA" || inst.Mnemonic == "SHLD":
+				x.Memory[inst.Value] = append(x.Memory[inst.Value], Ref{inst, Write})
+			case inst.Mnemonic == "LXI":
+				x.Memory[inst.Value] = append(x.Memory[inst.Value], Ref{inst, PointerLoad})
+			case inst.Mnemonic == "IN":
+				x.Ports[byte(inst.Value)] = append(x.Ports[byte(inst.Value)], Ref{inst, Read})
+			case inst.Mnemonic == "OUT":
+				x.Ports[byte(inst.Value)] = append(x.Ports[byte(inst.Value)], Ref{inst, Write})
+			}
+		}
+	}
+	return x
+}
+
+// WriteText writes a report with the references to each address, followed by those to each port. Addresses and
+// instructions are written as opts tells.
+func (x *Xrefs) WriteText(w io.Writer, opts Options) error {
+	bw := bufio.NewWriter(w)
+	addrs := make(map[uint16]bool)
+	for addr := range x.Memory {
+		addrs[addr] = true
+	}
+	for _, addr := range sortedAddrs(addrs) {
+		bw.WriteString(addrName(addr, opts) + "\n")
+		writeRefs(bw, x.Memory[addr], opts)
+	}
+
+	ports := make([]int, 0, len(x.Ports))
+	for port := range x.Ports {
+		ports = append(ports, int(port))
+	}
+	sort.Ints(ports)
+	for _, port := range ports {
+		fmt.Fprintf(bw, "port %02X\n", port)
+		writeRefs(bw, x.Ports[byte(port)], opts)
+	}
+	return bw.Flush()
+}
+
+// writeRefs writes an indented line with the address, access and instruction of each reference
+func writeRefs(w *bufio.Writer, refs []Ref, opts Options) {
+	for _, ref := range refs {
+		fmt.Fprintf(w, "\t%04X  %-7s  %s\n", ref.Inst.Addr, ref.Access, Format(ref.Inst, opts))
+	}
+}
+
+// Comments returns comments to be written inline by setting Options.Comments. The instructions reading, writing or
+// loading an address get the address and access, like "2072 read", so the variables in RAM are found on every line
+// using them, and the lines of the code referred to list the references to them, like "xref 0AEF exec, 0B05 read".
+func (x *Xrefs) Comments() map[uint16]string {
+	uses := make(map[uint16][]string)
+	xrefs := make(map[uint16]string, len(x.Memory))
+	for addr, refs := range x.Memory {
+		from := make([]string, len(refs))
+		for i, ref := range refs {
+			from[i] = fmt.Sprintf("%04X %s", ref.Inst.Addr, ref.Access)
+			if ref.Access != Exec {
+				uses[ref.Inst.Addr] = append(uses[ref.Inst.Addr], fmt.Sprintf("%04X %s", addr, ref.Access))
+			}
+		}
+		xrefs[addr] = "xref " + strings.Join(from, ", ")
+	}
+
+	comments := make(map[uint16]string, len(uses)+len(xrefs))
+	for addr, use := range uses {
+		comments[addr] = strings.Join(use, ", ")
+	}
+	for addr, xref := range xrefs {
+		if use, ok := comments[addr]; ok {
+			xref = use + "; " + xref
+		}
+		comments[addr] = xref
+	}
+	return comments
+}
@@ -0,0 +1,117 @@
+package dasm
+
+import (
+	"strings"
+)
+
+// Syntax is the notation instructions are written in
+type Syntax int
+
+const (
+	// Intel writes instructions with the mnemonics of the 8080 manuals: MOV A,M or JNZ $1234
+	Intel Syntax = iota
+	// Zilog writes instructions with the mnemonics of the Z80, which runs 8080 code: LD A,(HL) or JP NZ,1234h
+	Zilog
+)
+
+// zilog are the templates of the Z80 instructions corresponding to each 8080 mnemonic. {r1} and {r2} are the
+// register operands, {rp} the register pair, {n} the operand following the opcode, and {cc} the condition of
+// conditional jumps, calls and returns.
+var zilog = map[string]string{
+	"MOV":  "LD {r1},{r2}",
+	"MVI":  "LD {r1},{n}",
+	"LXI":  "LD {rp},{n}",
+	"LDA":  "LD A,({n})",
+	"STA":  "LD ({n}),A",
+	"LHLD": "LD HL,({n})",
+	"SHLD": "LD ({n}),HL",
+	"LDAX": "LD A,({rp})",
+	"STAX": "LD ({rp}),A",
+	"XCHG": "EX DE,HL",
+	"XTHL": "EX (SP),HL",
+	"SPHL": "LD SP,HL",
+	"PCHL": "JP (HL)",
+	"ADD":  "ADD A,{r1}",
+	"ADC":  "ADC A,{r1}",
+	"SUB":  "SUB {r1}",
+	"SBB":  "SBC A,{r1}",
+	"ANA":  "AND {r1}",
+	"XRA":  "XOR {r1}",
+	"ORA":  "OR {r1}",
+	"CMP":  "CP {r1}",
+	"ADI":  "ADD A,{n}",
+	"ACI":  "ADC A,{n}",
+	"SUI":  "SUB {n}",
+	"SBI":  "SBC A,{n}",
+	"ANI":  "AND {n}",
+	"XRI":  "XOR {n}",
+	"ORI":  "OR {n}",
+	"CPI":  "CP {n}",
+	"INR":  "INC {r1}",
+	"DCR":  "DEC {r1}",
+	"INX":  "INC {rp}",
+	"DCX":  "DEC {rp}",
+	"DAD":  "ADD HL,{rp}",
+	"RLC":  "RLCA",
+	"RRC":  "RRCA",
+	"RAL":  "RLA",
+	"RAR":  "RRA",
+	"CMA":  "CPL",
+	"STC":  "SCF",
+	"CMC":  "CCF",
+	"HLT":  "HALT",
+	"JMP":  "JP {n}",
+	"RST":  "RST {n}",
+	"PUSH": "PUSH {rp}",
+	"POP":  "POP {rp}",
+	"IN":   "IN A,({n})",
+	"OUT":  "OUT ({n}),A",
+}
+
+// zilogPairs are the Z80 names of the register pairs
+var zilogPairs = map[string]string{"B": "BC", "D": "DE", "H": "HL", "SP": "SP", "PSW": "AF"}
+
+// formatZilog formats the instruction with Z80 mnemonics
+func formatZilog(inst Instruction, opts *Options) string {
+	template, ok := zilog[inst.Mnemonic]
+	switch inst.Flow {
+	case CondJump:
+		template, ok = "JP {cc},{n}", true
+	case CondCall:
+		template, ok = "CALL {cc},{n}", true
+	case CondReturn:
+		template, ok = "RET {cc}", true
+	}
+	if !ok {
+		// the rest of the instructions are written the same in both syntaxes: NOP, DAA, DI, EI, CALL and RET
+		template = inst.Mnemonic
+		if inst.Operand != NoOperand {
+			template += " {n}"
+		}
+	}
+
+	regs := strings.Split(inst.Regs, ",")
+	r2 := ""
+	if len(regs) > 1 {
+		r2 = regs[1]
+	}
+	n := opts.operand(inst)
+	if inst.Operand == Vector {
+		n = opts.hex(inst.Target, 2)
+	}
+	return strings.NewReplacer(
+		"{r1}", zilogRegister(regs[0]),
+		"{r2}", zilogRegister(r2),
+		"{rp}", zilogPairs[regs[0]],
+		"{n}", n,
+		"{cc}", inst.Mnemonic[1:],
+	).Replace(template)
+}
+
+// zilogRegister returns the Z80 name of an 8-bit register operand
+func zilogRegister(r string) string {
+	if r == "M" {
+		return "(HL)"
+	}
+	return r
+}
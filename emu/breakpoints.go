@@ -0,0 +1,160 @@
+package emu
+
+import (
+	"sort"
+)
+
+// Breakpoints is the set of addresses where Run hands control back: execution breakpoints stop the computer before the
+// instruction at the address is executed, and watchpoints right after the instruction that writes to the address.
+type Breakpoints struct {
+	exec  map[uint16]bool
+	watch map[uint16]bool
+}
+
+// NewBreakpoints creates an empty set of breakpoints
+func NewBreakpoints() *Breakpoints {
+	return &Breakpoints{
+		exec:  make(map[uint16]bool),
+		watch: make(map[uint16]bool),
+	}
+}
+
+// Break sets an execution breakpoint at the given address
+func (b *Breakpoints) Break(addr uint16) {
+	b.exec[addr] = true
+}
+
+// Clear removes the execution breakpoint at the given address
+func (b *Breakpoints) Clear(addr uint16) {
+	delete(b.exec, addr)
+}
+
+// Watch sets a watchpoint on writes to the given address
+func (b *Breakpoints) Watch(addr uint16) {
+	b.watch[addr] = true
+}
+
+// Unwatch removes the watchpoint on the given address
+func (b *Breakpoints) Unwatch(addr uint16) {
+	delete(b.watch, addr)
+}
+
+// IsBreak returns whether there is an execution breakpoint at the given address
+func (b *Breakpoints) IsBreak(addr uint16) bool {
+	return b.exec[addr]
+}
+
+// IsWatched returns whether there is a watchpoint on the given address
+func (b *Breakpoints) IsWatched(addr uint16) bool {
+	return b.watch[addr]
+}
+
+// Breaks returns the addresses of the execution breakpoints in ascending order
+func (b *Breakpoints) Breaks() []uint16 {
+	return sortedAddrs(b.exec)
+}
+
+// Watches returns the watched addresses in ascending order
+func (b *Breakpoints) Watches() []uint16 {
+	return sortedAddrs(b.watch)
+}
+
+func sortedAddrs(set map[uint16]bool) []uint16 {
+	addrs := make([]uint16, 0, len(set))
+	for addr := range set {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	return addrs
+}
+
+// StopReason tells why Run handed control back
+type StopReason int
+
+const (
+	// StopBreakpoint means the PC reached an execution breakpoint
+	StopBreakpoint StopReason = iota
+	// StopWatchpoint means a watched address was written
+	StopWatchpoint
+	// StopRequested means the stop predicate given to Run returned true
+	StopRequested
+	// StopError means the last instruction could not be executed
+	StopError
+)
+
+// Stop describes the state in which Run handed control back
+type Stop struct {
+	Reason StopReason
+	// Addr is the address of the breakpoint, or the watched address, that stopped the computer
+	Addr uint16
+	// Err is the error the computer faced when Reason is StopError
+	Err error
+}
+
+// Run executes instructions until a breakpoint or watchpoint in bp is hit, an instruction fails, or stop returns true.
+//
+// bp and stop can be nil. stop is checked before executing each instruction but the first, and so are the execution
+// breakpoints, which means that running from an address with a breakpoint moves past it.
+func (c *Computer) Run(bp *Breakpoints, stop func() bool) Stop {
+	if bp == nil {
+		bp = NewBreakpoints()
+	}
+
+	var watched []uint16
+	c.onWrite = func(addr uint16) {
+		if bp.watch[addr] {
+			watched = append(watched, addr)
+		}
+	}
+	defer func() { c.onWrite = nil }()
+
+	for first := true; ; first = false {
+		if !first {
+			if bp.exec[c.PC] {
+				return Stop{Reason: StopBreakpoint, Addr: c.PC}
+			}
+			if stop != nil && stop() {
+				return Stop{Reason: StopRequested, Addr: c.PC}
+			}
+		}
+
+		if err := c.Step(nil); err != nil {
+			return Stop{Reason: StopError, Addr: c.PC, Err: err}
+		}
+		if len(watched) > 0 {
+			return Stop{Reason: StopWatchpoint, Addr: watched[0]}
+		}
+	}
+}
+
+// Next executes the next instruction like Run does when stop returns true right away. The exception are calls: in
+// that case the subroutine is run until it returns, or Run stops for any other reason.
+func (c *Computer) Next(bp *Breakpoints, stop func() bool) Stop {
+	sp := c.SP
+	op, _ := c.read8(c.PC)
+	call := isCall(op)
+	return c.Run(bp, func() bool {
+		return !call || c.SP >= sp || (stop != nil && stop())
+	})
+}
+
+// Finish runs the current subroutine until it returns, or Run stops for any other reason.
+func (c *Computer) Finish(bp *Breakpoints, stop func() bool) Stop {
+	sp := c.SP
+	prev, _ := c.read8(c.PC)
+	return c.Run(bp, func() bool {
+		returned := isReturn(prev) && c.SP > sp
+		prev, _ = c.read8(c.PC)
+		return returned || (stop != nil && stop())
+	})
+}
+
+// isCall tells whether the opcode is a CALL, a conditional call or a RST
+func isCall(op byte) bool {
+	return op == 0xCD || op&0xC7 == 0xC4 || op&0xC7 == 0xC7
+}
+
+// isReturn tells whether the opcode is a RET or a conditional return
+func isReturn(op byte) bool {
+	return op == 0xC9 || op&0xC7 == 0xC0
+}
@@ -3,8 +3,13 @@ package emu
 
 import (
 	"fmt"
+	"io"
 	"math"
 	"strings"
+
+	"github.com/miguelff/8080/encoding/ihex"
+	"github.com/miguelff/8080/isa"
+	"github.com/miguelff/8080/symbols"
 )
 
 const (
@@ -169,10 +174,27 @@ func (c *CPU) HL() uint16 {
 	return uint16(c.H)<<8 + uint16(c.L)
 }
 
+// PortHandler is implemented by the devices wired to the I/O ports of the computer. The CPU reaches them through
+// the IN and OUT instructions.
+type PortHandler interface {
+	// In returns the byte the device puts on the data bus when the CPU reads the given port
+	In(port byte) (byte, error)
+	// Out receives the byte the CPU writes to the given port
+	Out(port byte, v byte) error
+}
+
 // Computer connects the Memory and the cpu
 type Computer struct {
 	CPU
 	Mem []byte
+
+	// Symbols, when set, names the addresses shown in the debug trace, and shows the source code assembled at them
+	// when read from a listing
+	Symbols *symbols.Table
+
+	ports map[byte]PortHandler
+	// onWrite, when set, is notified of every address written to memory
+	onWrite func(addr uint16)
 }
 
 // newComputer creates a new computer with the cpu and memory states given
@@ -190,6 +212,46 @@ func Load(rom []byte) *Computer {
 	return c
 }
 
+// LoadAt loads the program into a newly created computer main Memory, starting at the given address, and points the
+// program counter to it. CP/M programs (.COM files), for instance, are loaded at 0x100.
+func LoadAt(bin []byte, addr uint16) *Computer {
+	c := newComputer(CPU{PC: addr}, make([]byte, MemSize))
+	if int(addr) < len(c.Mem) {
+		copy(c.Mem[addr:], bin)
+	}
+	return c
+}
+
+// LoadHex loads the program read in Intel HEX format into a newly created computer main Memory, at the addresses of
+// its records, and points the program counter to the lowest one
+func LoadHex(r io.Reader) (*Computer, error) {
+	segments, err := ihex.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	c := newComputer(CPU{}, make([]byte, MemSize))
+	for i, s := range segments {
+		if int(s.Addr)+len(s.Data) > len(c.Mem) {
+			return nil, fmt.Errorf("data at %04X-%04X beyond the memory", s.Addr, int(s.Addr)+len(s.Data)-1)
+		}
+		copy(c.Mem[s.Addr:], s.Data)
+		if i == 0 {
+			c.PC = s.Addr
+		}
+	}
+	return c, nil
+}
+
+// Attach wires the given handler to each of the given ports, replacing any handler previously attached to them.
+func (c *Computer) Attach(h PortHandler, ports ...byte) {
+	if c.ports == nil {
+		c.ports = make(map[byte]PortHandler)
+	}
+	for _, p := range ports {
+		c.ports[p] = h
+	}
+}
+
 // snapshot creates a copy of the current state of the computer
 func (c *Computer) snapshot() *Computer {
 	return newComputer(c.CPU, c.Mem)
@@ -237,14 +299,22 @@ func (c *Computer) String() string {
 	return s
 }
 
+// Implemented tells whether the emulator executes the instruction with the given opcode
+func Implemented(op byte) bool {
+	return int(op) < len(it) && it[op] != nil
+}
+
 // Step executes one instruction of the code pointed by the Program Counter (PC) of the CPU
 func (c *Computer) Step(df DebugFilter) error {
 	op, err := c.read8(c.PC)
 	if err != nil {
 		return err
 	}
-	if int(op) >= len(it) || it[op] == nil {
-		return fmt.Errorf("unimplemented op %02X", op)
+	if !Implemented(op) {
+		if def := isa.Opcodes[op]; def.Defined() {
+			return fmt.Errorf("unimplemented op %02X (%s)", op, def)
+		}
+		return fmt.Errorf("undefined op %02X", op)
 	}
 
 	if df != nil && df(op) {
@@ -274,20 +344,41 @@ func (c *Computer) read16(addr uint16) (uint16, error) {
 }
 
 func (c *Computer) read8(addr uint16) (byte, error) {
-	if int(addr) > len(c.Mem) {
+	if int(addr) >= len(c.Mem) {
 		return 0, ComputerError(fmt.Sprintf("segfault accessing %04X", addr))
 	}
 	return c.Mem[addr], nil
 }
 
 func (c *Computer) write8(addr uint16, d8 byte) error {
-	if int(addr) > len(c.Mem) {
+	if int(addr) >= len(c.Mem) {
 		return ComputerError(fmt.Sprintf("segfault accessing %04X", addr))
 	}
 	c.Mem[addr] = d8
+	if c.onWrite != nil {
+		c.onWrite(addr)
+	}
 	return nil
 }
 
+// in reads from the device attached to the given port. Reading a port with nothing attached yields 0.
+func (c *Computer) in(port byte) (byte, error) {
+	h, ok := c.ports[port]
+	if !ok {
+		return 0, nil
+	}
+	return h.In(port)
+}
+
+// out writes to the device attached to the given port. Writes to a port with nothing attached are discarded.
+func (c *Computer) out(port byte, v byte) error {
+	h, ok := c.ports[port]
+	if !ok {
+		return nil
+	}
+	return h.Out(port, v)
+}
+
 func (c *Computer) read8Indirect() (byte, error) {
 	return c.read8(c.HL())
 }
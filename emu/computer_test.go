@@ -2,13 +2,18 @@ package emu
 
 import (
 	"bytes"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/miguelff/8080/encoding"
+	"github.com/miguelff/8080/isa"
 )
 
 func ram(bytes string) []byte {
-	return encoding.HexToBin(bytes)
+	return encoding.MustParseHex(bytes)
 }
 func TestParity(t *testing.T) {
 	for _, tC := range []struct {
@@ -1027,7 +1032,7 @@ func TestComputer_Step(t *testing.T) {
 				CPU{
 					H: 0x02,
 				},
-				ram("20"),
+				ram("25"),
 			),
 			newComputer(
 				CPU{
@@ -1035,7 +1040,7 @@ func TestComputer_Step(t *testing.T) {
 					PC:    0x01,
 					Flags: hc,
 				},
-				ram("20"),
+				ram("25"),
 			),
 		},
 		{
@@ -3303,7 +3308,7 @@ func TestComputer_Step(t *testing.T) {
 				CPU{
 					A: 0xFF,
 				},
-				ram("A8"),
+				ram("AF"),
 			),
 			newComputer(
 				CPU{
@@ -3311,7 +3316,7 @@ func TestComputer_Step(t *testing.T) {
 					PC:    0x01,
 					Flags: zf | pf,
 				},
-				ram("A8"),
+				ram("AF"),
 			),
 		},
 		{
@@ -3321,7 +3326,7 @@ func TestComputer_Step(t *testing.T) {
 					A: 0xFF,
 					B: 0x0A,
 				},
-				ram("A9"),
+				ram("A8"),
 			),
 			newComputer(
 				CPU{
@@ -3330,7 +3335,7 @@ func TestComputer_Step(t *testing.T) {
 					PC:    0x01,
 					Flags: pf | sf,
 				},
-				ram("A9"),
+				ram("A8"),
 			),
 		},
 		{
@@ -3340,7 +3345,7 @@ func TestComputer_Step(t *testing.T) {
 					A: 0xFF,
 					C: 0x0A,
 				},
-				ram("AA"),
+				ram("A9"),
 			),
 			newComputer(
 				CPU{
@@ -3349,7 +3354,7 @@ func TestComputer_Step(t *testing.T) {
 					PC:    0x01,
 					Flags: pf | sf,
 				},
-				ram("AA"),
+				ram("A9"),
 			),
 		},
 		{
@@ -3359,7 +3364,7 @@ func TestComputer_Step(t *testing.T) {
 					A: 0xFF,
 					D: 0x0A,
 				},
-				ram("AB"),
+				ram("AA"),
 			),
 			newComputer(
 				CPU{
@@ -3368,7 +3373,7 @@ func TestComputer_Step(t *testing.T) {
 					PC:    0x01,
 					Flags: pf | sf,
 				},
-				ram("AB"),
+				ram("AA"),
 			),
 		},
 		{
@@ -3378,7 +3383,7 @@ func TestComputer_Step(t *testing.T) {
 					A: 0xFF,
 					E: 0x0A,
 				},
-				ram("AC"),
+				ram("AB"),
 			),
 			newComputer(
 				CPU{
@@ -3387,7 +3392,7 @@ func TestComputer_Step(t *testing.T) {
 					PC:    0x01,
 					Flags: pf | sf,
 				},
-				ram("AC"),
+				ram("AB"),
 			),
 		},
 		{
@@ -3397,7 +3402,7 @@ func TestComputer_Step(t *testing.T) {
 					A: 0xFF,
 					H: 0x0A,
 				},
-				ram("AD"),
+				ram("AC"),
 			),
 			newComputer(
 				CPU{
@@ -3406,7 +3411,7 @@ func TestComputer_Step(t *testing.T) {
 					PC:    0x01,
 					Flags: pf | sf,
 				},
-				ram("AD"),
+				ram("AC"),
 			),
 		},
 		{
@@ -3416,7 +3421,7 @@ func TestComputer_Step(t *testing.T) {
 					A: 0xFF,
 					L: 0x0A,
 				},
-				ram("AF"),
+				ram("AD"),
 			),
 			newComputer(
 				CPU{
@@ -3425,7 +3430,7 @@ func TestComputer_Step(t *testing.T) {
 					PC:    0x01,
 					Flags: pf | sf,
 				},
-				ram("AF"),
+				ram("AD"),
 			),
 		},
 	} {
@@ -3440,3 +3445,127 @@ func TestComputer_Step(t *testing.T) {
 		})
 	}
 }
+
+type fakeDevice struct {
+	in  byte
+	out []byte
+}
+
+func (d *fakeDevice) In(_ byte) (byte, error) {
+	return d.in, nil
+}
+
+func (d *fakeDevice) Out(_ byte, v byte) error {
+	d.out = append(d.out, v)
+	return nil
+}
+
+func TestComputer_Ports(t *testing.T) {
+	dev := &fakeDevice{in: 0x42}
+	c := newComputer(CPU{A: 0x07}, ram("D3 11 DB 11 DB 12"))
+	c.Attach(dev, 0x11)
+
+	for i := 0; i < 3; i++ {
+		if err := c.Step(DebugNone); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if !bytes.Equal(dev.out, []byte{0x07}) {
+		t.Errorf("OUT: got %X, want 07", dev.out)
+	}
+	if c.A != 0x00 {
+		t.Errorf("IN from an unattached port: got A=%02X, want 00", c.A)
+	}
+	if c.PC != 0x06 {
+		t.Errorf("got PC=%04X, want 0006", c.PC)
+	}
+
+	c.PC = 0x02
+	if err := c.Step(DebugNone); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if c.A != 0x42 {
+		t.Errorf("IN: got A=%02X, want 42", c.A)
+	}
+}
+
+func TestComputer_Run(t *testing.T) {
+	c := newComputer(CPU{H: 0x00, L: 0x10}, ram(`
+		0000: 04        ; INR B
+		0001: 70        ; MOV M,B
+		0002: C3 00 00  ; JMP $0000
+		0005: 08        ; undefined
+		0006: 00 00 00 00 00 00 00 00 00 00 00
+	`))
+	bp := NewBreakpoints()
+	bp.Break(0x01)
+	bp.Watch(0x10)
+
+	if st := c.Run(bp, nil); st.Reason != StopBreakpoint || st.Addr != 0x01 {
+		t.Errorf("got %+v, want a stop on the breakpoint at 0001", st)
+	}
+	if st := c.Run(bp, nil); st.Reason != StopWatchpoint || st.Addr != 0x10 {
+		t.Errorf("got %+v, want a stop on the watchpoint at 0010", st)
+	}
+
+	bp.Clear(0x01)
+	bp.Unwatch(0x10)
+	stop := func() bool { return c.B == 0x05 }
+	if st := c.Run(bp, stop); st.Reason != StopRequested || c.PC != 0x01 {
+		t.Errorf("got %+v at PC %04X, want a requested stop at 0001", st, c.PC)
+	}
+
+	c.PC = 0x05
+	if st := c.Run(bp, nil); st.Reason != StopError || st.Err == nil {
+		t.Errorf("got %+v, want a stop on an error", st)
+	}
+}
+
+func TestLoadHex(t *testing.T) {
+	c, err := LoadHex(strings.NewReader(":03010000C3000138\n:012000007669\n:00000001FF\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.PC != 0x100 || !bytes.Equal(c.Mem[0x100:0x103], ram("C3 00 01")) || c.Mem[0x2000] != 0x76 {
+		t.Errorf("got PC=%04X, memory % X at 0100 and %02X at 2000", c.PC, c.Mem[0x100:0x103], c.Mem[0x2000])
+	}
+
+	if _, err := LoadHex(strings.NewReader(":01400000FFC0\n:00000001FF\n")); err == nil {
+		t.Error("got no error loading data beyond the memory")
+	}
+}
+
+// TestInstructionSet checks the instructions emulated are the ones package isa assigns to their opcodes
+func TestInstructionSet(t *testing.T) {
+	for op, inst := range it {
+		if inst == nil {
+			continue
+		}
+		def := isa.Opcodes[op]
+		if !def.Defined() {
+			t.Errorf("0x%02X: undefined opcode emulated", op)
+			continue
+		}
+		name := runtime.FuncForPC(reflect.ValueOf(inst).Pointer()).Name()
+		name = name[strings.LastIndex(name, ".")+1:]
+		if want := instructionName(def); name != want {
+			t.Errorf("0x%02X: %s emulated by %s, want %s", op, def, name, want)
+		}
+	}
+}
+
+// instructionName returns the name of the function emulating the instruction, like movbc for MOV B,C, movfrommb for
+// MOV B,M and movtomb for MOV M,B
+func instructionName(def isa.Opcode) string {
+	regs := strings.ToLower(strings.Replace(def.Regs, ",", "", 1))
+	switch {
+	case def.Mnemonic == "MOV" && regs[1] == 'm':
+		return "movfromm" + regs[:1]
+	case def.Mnemonic == "MOV" && regs[0] == 'm':
+		return "movtom" + regs[1:]
+	case def.Operand == isa.Vector:
+		regs = strconv.Itoa(def.Vector)
+	}
+	return strings.ToLower(def.Mnemonic) + regs
+}
@@ -21,36 +21,45 @@ func DebugAll(_ byte) bool { return true }
 func DebugNone(_ byte) bool { return false }
 
 // MakeDebugFilter creates a DebugFilter that will select the
-// opcodes denoted by the given string.
+// opcodes denoted by the given string, in any of the formats
+// understood by encoding.ParseHex.
 //
 // MakeDebugFilter("all") will debug all symbols
 // MakeDebugFilter("C9 CD") will debug CALL and RET instructions
-func MakeDebugFilter(def string) DebugFilter {
+func MakeDebugFilter(def string) (DebugFilter, error) {
 	if def == "all" {
-		return DebugAll
-	} else {
-		opcodes := encoding.HexToBin(def)
+		return DebugAll, nil
+	}
+	opcodes, err := encoding.ParseHex(def)
+	if err != nil {
+		return nil, fmt.Errorf("invalid opcodes %q: %v", def, err)
+	}
 
-		return func(opcode byte) bool {
-			for i := range opcodes {
-				if opcode == opcodes[i] {
-					return true
-				}
+	return func(opcode byte) bool {
+		for i := range opcodes {
+			if opcode == opcodes[i] {
+				return true
 			}
-			return false
 		}
-	}
+		return false
+	}, nil
 }
 
 func (c *Computer) debug(prev *Computer) {
 	context := make([]byte, 4)
 	copy(context, prev.Mem[prev.PC:])
-	assembly, err := dasm.DisassembleFirst(context)
+	inst, err := dasm.Decode(context, prev.PC)
 
+	if name, ok := c.Symbols.Name(prev.PC); ok {
+		fmt.Printf("%s:\n", name)
+	}
+	if line, ok := c.Symbols.Line(prev.PC); ok {
+		fmt.Printf("%s\n", line)
+	}
 	if err != nil {
 		fmt.Printf("Error dissassembing bytes: %v\n", err)
 	} else {
-		fmt.Printf("(%s) PC: %04X  Mem(%04X-%04X): %s | %s \n", prev.Flags, prev.PC, prev.PC, prev.PC+4, hex.EncodeToString(context), strings.TrimSpace(assembly))
+		fmt.Printf("(%s) PC: %04X  Mem(%04X-%04X): %s | %s \n", prev.Flags, prev.PC, prev.PC, prev.PC+4, hex.EncodeToString(context), dasm.Format(inst, dasm.Options{Symbols: c.Symbols}))
 	}
 	fmt.Println(prev.diff(c))
 }
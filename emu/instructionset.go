@@ -28,10 +28,10 @@ var it = []Instruction{
 	0x1C: inre,
 	0x1D: dcre,
 	0x1E: mvie,
-	0x20: dcrh,
 	0x21: lxih,
 	0x23: inxh,
 	0x24: inrh,
+	0x25: dcrh,
 	0x26: mvih,
 	0x29: dadh,
 	0x2C: inrl,
@@ -145,13 +145,13 @@ var it = []Instruction{
 	0xA4: anah,
 	0xA5: anal,
 	0xA7: anaa,
-	0xA8: xraa,
-	0xA9: xrab,
-	0xAA: xrac,
-	0xAB: xrad,
-	0xAC: xrae,
-	0xAD: xrah,
-	0xAF: xral,
+	0xA8: xrab,
+	0xA9: xrac,
+	0xAA: xrad,
+	0xAB: xrae,
+	0xAC: xrah,
+	0xAD: xral,
+	0xAF: xraa,
 	0xB0: orab,
 	0xB1: orac,
 	0xB2: orad,
@@ -172,8 +172,10 @@ var it = []Instruction{
 	0xC9: ret,
 	0xCD: call,
 	0xCF: rst1,
+	0xD3: out,
 	0xD5: pushd,
 	0xD7: rst2,
+	0xDB: in,
 	0xDF: rst3,
 	0xE6: ani,
 	0xE7: rst4,
@@ -425,7 +427,7 @@ func dcre(c *Computer) error {
 	return dcr(c, &c.E)
 }
 
-// 0x20	DCR H | H <- H -1 (Z, S, P, AC)
+// 0x25	DCR H | H <- H -1 (Z, S, P, AC)
 func dcrh(c *Computer) error {
 	return dcr(c, &c.H)
 }
@@ -435,6 +437,22 @@ func dcrl(c *Computer) error {
 	return dcr(c, &c.L)
 }
 
+// 0xDB IN D8 | A <- port(D8)
+// Reads a byte from the device attached to the port denoted by the next byte.
+func in(c *Computer) error {
+	port, err := c.read8(c.PC + 1)
+	if err != nil {
+		return err
+	}
+	v, err := c.in(port)
+	if err != nil {
+		return err
+	}
+	c.A = v
+	c.PC += 2
+	return nil
+}
+
 // 0x3C	INR A | A <- A+1 (Z, S, P, AC)
 func inra(c *Computer) error {
 	return inr(c, &c.A)
@@ -948,6 +966,21 @@ func oral(c *Computer) error {
 	return ora(c, c.L)
 }
 
+// 0xD3 OUT D8 | port(D8) <- A
+// Writes the accumulator to the device attached to the port denoted by the next byte.
+func out(c *Computer) error {
+	port, err := c.read8(c.PC + 1)
+	if err != nil {
+		return err
+	}
+	err = c.out(port, c.A)
+	if err != nil {
+		return err
+	}
+	c.PC += 2
+	return nil
+}
+
 // 0xD5	PUSH D | (sp-2)<-E; (sp-1)<-D; sp <- sp - 2
 func pushd(c *Computer) error {
 	err := push(c, c.DE())
@@ -1102,37 +1135,37 @@ func subl(c *Computer) error {
 	return sub(c, c.L, false)
 }
 
-// 0xA8 XRA A | A <- A XOR A (Z, S, P, CY)
+// 0xAF XRA A | A <- A XOR A (Z, S, P, CY)
 func xraa(c *Computer) error {
 	return xra(c, c.A)
 }
 
-// 0xA9 XRA B | A <- A XOR B (Z, S, P, CY)
+// 0xA8 XRA B | A <- A XOR B (Z, S, P, CY)
 func xrab(c *Computer) error {
 	return xra(c, c.B)
 }
 
-// 0xAA XRA C | A <- A XOR C (Z, S, P, CY)
+// 0xA9 XRA C | A <- A XOR C (Z, S, P, CY)
 func xrac(c *Computer) error {
 	return xra(c, c.C)
 }
 
-// 0xAB XRA D | A <- A XOR D (Z, S, P, CY)
+// 0xAA XRA D | A <- A XOR D (Z, S, P, CY)
 func xrad(c *Computer) error {
 	return xra(c, c.D)
 }
 
-// 0xAC XRA E | A <- A XOR E (Z, S, P, CY)
+// 0xAB XRA E | A <- A XOR E (Z, S, P, CY)
 func xrae(c *Computer) error {
 	return xra(c, c.E)
 }
 
-// 0xAD XRA H | A <- A XOR H (Z, S, P, CY)
+// 0xAC XRA H | A <- A XOR H (Z, S, P, CY)
 func xrah(c *Computer) error {
 	return xra(c, c.H)
 }
 
-// 0xAF XRA L | A <- A XOR L (Z, S, P, CY)
+// 0xAD XRA L | A <- A XOR L (Z, S, P, CY)
 func xral(c *Computer) error {
 	return xra(c, c.L)
 }
@@ -1,15 +1,193 @@
 package encoding
 
 import (
-	"encoding/hex"
+	"fmt"
+	"strconv"
 	"strings"
 )
 
-// HexToBin converts a string containing hexadecimal digits and whitespace to its binary representation. Whitespace is
-// ignored. It assumes hexStr represents a correct hexadecimal string.
-func HexToBin(hexStr string) []byte {
-	b := []byte(strings.ReplaceAll(hexStr, " ", ""))
-	in := make([]byte, hex.DecodedLen(len(b)))
-	_, _ = hex.Decode(in, b)
-	return in
+// ParseHex parses hexadecimal digits into the bytes they represent. The digits can be separated by whitespace,
+// including newlines and tabs, and bytes can be split across separators. Comments start with ; or # and go up to the
+// end of the line. Lines can start with an address column ending with a colon, or, in hexdump -C output, of 8 digits
+// followed by two spaces on every line, as in these formats:
+//
+//	0100: 3E 41 C3 00 01     ; MVI A,'A' ; JMP 0100H
+//	00000000: 3e41 c300 013b                  >A...;       (xxd)
+//	00000000  3e 41 c3 00 01 3b                 |>A...;|   (hexdump -C)
+//
+// The address of each line must follow on from the bytes before it. The ASCII gutters of xxd, following two spaces,
+// and hexdump -C, between bars, are ignored when every line is laid out like their output. The lines holding a single * in hexdump -C output, standing for
+// repetitions of the previous line, are expanded up to the address of the following one. Errors are reported with
+// their line and column.
+func ParseHex(s string) ([]byte, error) {
+	lines := strings.Split(s, "\n")
+	hexdump, xxd := isHexdump(lines), isXxd(lines)
+
+	var bin []byte
+	var first, prevStart int
+	var repeat, addressed bool
+	for n, line := range lines {
+		pos := func(col int) string { return fmt.Sprintf("line %d, column %d", n+1, col+1) }
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "*" {
+			repeat = true
+			continue
+		}
+
+		data, start, addr, hasAddr := splitAddress(line, hexdump)
+		if hasAddr {
+			if !addressed {
+				first, addressed = addr-len(bin), true
+			}
+			if repeat {
+				if addr-first < len(bin) {
+					return nil, fmt.Errorf("%s: address %X before the end of the repeated line", pos(0), addr)
+				}
+				prev := append([]byte(nil), bin[prevStart:]...)
+				for len(prev) > 0 && len(bin) < addr-first {
+					bin = append(bin, prev[:min(len(prev), addr-first-len(bin))]...)
+				}
+			}
+			if addr != first+len(bin) {
+				return nil, fmt.Errorf("%s: address %X doesn't follow on from the previous line, ending at %X", pos(0), addr, first+len(bin))
+			}
+		}
+		repeat = false
+		// xxd writes the ASCII gutter after two spaces, and hexdump -C between bars
+		if i := strings.Index(data, "  "); xxd && i >= 0 {
+			data = data[:i]
+		}
+		if i := strings.IndexByte(data, '|'); hexdump && i >= 0 {
+			data = data[:i]
+		}
+		if i := strings.IndexAny(data, ";#"); i >= 0 {
+			data = data[:i]
+		}
+
+		lineStart := len(bin)
+		var digits []byte
+		var col int
+		for i := 0; i < len(data); i++ {
+			c := data[i]
+			if c == ' ' || c == '\t' {
+				continue
+			}
+			if !isHexDigit(c) {
+				return nil, fmt.Errorf("%s: invalid hexadecimal digit %q", pos(start+i), c)
+			}
+			if len(digits) == 0 {
+				col = start + i
+			}
+			digits = append(digits, c)
+			if len(digits) == 2 {
+				v, _ := strconv.ParseUint(string(digits), 16, 8)
+				bin = append(bin, byte(v))
+				digits = digits[:0]
+			}
+		}
+		if len(digits) != 0 {
+			return nil, fmt.Errorf("%s: odd number of hexadecimal digits", pos(col))
+		}
+		if len(bin) > lineStart {
+			prevStart = lineStart
+		}
+	}
+	return bin, nil
+}
+
+// hexdumpAddress is the number of digits of the addresses written by hexdump -C and xxd
+const hexdumpAddress = 8
+
+// isHexdump tells whether the lines are hexdump -C output: every line holding data starts with an address of 8
+// digits followed by two spaces, or holds just the address, like the last one, or a single *
+func isHexdump(lines []string) bool {
+	found := false
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if t := strings.TrimSpace(line); t == "" || t == "*" {
+			continue
+		}
+		if len(line) < hexdumpAddress || strings.TrimLeft(line[:hexdumpAddress], "0123456789abcdefABCDEF") != "" {
+			return false
+		}
+		if rest := line[hexdumpAddress:]; rest != "" && !strings.HasPrefix(rest, "  ") {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// isXxd tells whether the lines are xxd output: every line holding data starts with an address of 8 digits followed
+// by a colon and a space, then groups of digits separated by single spaces, and the ASCII gutter after two spaces
+func isXxd(lines []string) bool {
+	found := false
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if len(line) < hexdumpAddress+2 || strings.TrimLeft(line[:hexdumpAddress], "0123456789abcdefABCDEF") != "" ||
+			line[hexdumpAddress:hexdumpAddress+2] != ": " {
+			return false
+		}
+		data := line[hexdumpAddress+2:]
+		i := strings.Index(data, "  ")
+		if i < 0 {
+			return false
+		}
+		for _, group := range strings.Split(data[:i], " ") {
+			if group == "" || strings.TrimLeft(group, "0123456789abcdefABCDEF") != "" {
+				return false
+			}
+		}
+		found = true
+	}
+	return found
+}
+
+// splitAddress splits the address column off the line, if any, returning the rest of the line and where it starts.
+// Addresses without a colon are only told apart from data in hexdump -C output.
+func splitAddress(line string, hexdump bool) (data string, start int, addr int, ok bool) {
+	i := 0
+	for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+		i++
+	}
+	j := i
+	for j < len(line) && isHexDigit(line[j]) {
+		j++
+	}
+	switch {
+	case j > i && j < len(line) && line[j] == ':':
+		j++
+	case hexdump && i == 0 && j == hexdumpAddress:
+	default:
+		return line, 0, 0, false
+	}
+	v, err := strconv.ParseUint(strings.TrimSuffix(line[i:j], ":"), 16, 32)
+	if err != nil {
+		return line, 0, 0, false
+	}
+	return line[j:], j, int(v), true
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// MustParseHex works like ParseHex, but panics when the string isn't valid. It's meant for fixed inputs, like test
+// fixtures.
+func MustParseHex(s string) []byte {
+	bin, err := ParseHex(s)
+	if err != nil {
+		panic(fmt.Sprintf("MustParseHex: %v", err))
+	}
+	return bin
 }
@@ -6,9 +6,9 @@ import (
 	"testing"
 )
 
-func TestHexToBin(t *testing.T) {
+func TestMustParseHex(t *testing.T) {
 	str := "00 D0 C1"
-	bin := HexToBin(str)
+	bin := MustParseHex(str)
 
 	want := strings.ReplaceAll(str, " ", "")
 	got := fmt.Sprintf("%X", bin)
@@ -16,3 +16,62 @@ func TestHexToBin(t *testing.T) {
 		t.Errorf("got %s \n\n want %s", got, want)
 	}
 }
+
+func TestParseHex(t *testing.T) {
+	for _, tC := range []struct {
+		desc    string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{"empty", "", "", ""},
+		{"whitespace", "3e41\tC3 0 0\n01\r\n", "3E41C30001", ""},
+		{"comments", "3E 41 ; MVI A,'A'\n# JMP 0100H\nC3 00 01 # jump", "3E41C30001", ""},
+		{"addresses", "0100: 3E 41\n0102: C3 00 01\n0105: 76\n0106: C9", "3E41C3000176C9", ""},
+		{"digits before two spaces", "C30001  3E41", "C300013E41", ""},
+		{"short digits before two spaces", "3E41  C3", "3E41C3", ""},
+		{"lines without addresses", "0100: 3E 41\nC3 00 01", "3E41C30001", ""},
+		{"two spaces after the address", "0100:  3E 41 C3", "3E41C3", ""},
+		{"two spaces between bytes", "0100: 3E 41 C3 00  01 02 03 04", "3E41C30001020304", ""},
+		{"gutter outside xxd output", "0100: 3E 41  >A", "", "line 1, column 14: invalid hexadecimal digit '>'"},
+		{
+			"xxd",
+			"00000000: 3e41 c300 013b 237c 6865 6c6c 6f20 776f  >A...;#|hello wo\n" +
+				"00000010: 0000 6265                                ..be\n",
+			"3E41C300013B237C68656C6C6F20776F00006265",
+			"",
+		},
+		{
+			"hexdump -C",
+			"00000000  3e 41 c3 00 01 3b 23 7c  00 00 00 00 00 00 00 00  |>A...;#|........|\n" +
+				"00000010  00 00 00 00 00 00 00 00  00 00 00 00 00 00 00 00  |................|\n" +
+				"*\n" +
+				"00000030  62 65                                             |be|\n" +
+				"00000032\n",
+			"3E41C300013B237C" + strings.Repeat("00", 0x28) + "6265",
+			"",
+		},
+		{"invalid digit", "3E 41\nC3 0G 01", "", "line 2, column 5: invalid hexadecimal digit 'G'"},
+		{"odd digits", "3E 41\n  C3 0", "", "line 2, column 6: odd number of hexadecimal digits"},
+		{"address gap", "0100: 3E\n0200: 41", "", "line 2, column 1: address 200 doesn't follow on from the previous line, ending at 101"},
+		{"address overlap", "0100: 3E 41\n0101: C3", "", "line 2, column 1: address 101 doesn't follow on from the previous line, ending at 102"},
+		{"hexdump end", "00000000  3e 41\n00000004\n", "", "line 2, column 1: address 4 doesn't follow on from the previous line, ending at 2"},
+		{"repeat backwards", "00000010  00 01\n*\n00000008  02", "", "line 3, column 1: address 8 before the end of the repeated line"},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			bin, err := ParseHex(tC.in)
+			if tC.wantErr != "" {
+				if err == nil || err.Error() != tC.wantErr {
+					t.Errorf("got error %v, want %q", err, tC.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%X", bin); got != tC.want {
+				t.Errorf("got %s, want %s", got, tC.want)
+			}
+		})
+	}
+}
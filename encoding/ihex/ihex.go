@@ -0,0 +1,169 @@
+// ihex reads and writes programs in the Intel HEX format, in which most 8080 ROMs and assembler outputs are
+// distributed.
+//
+// Files are made of records, one per line, like:
+//
+//	:10010000214601360121470136007EFE09D2190140
+//	:00000001FF
+//
+// Each one starts with a colon, followed by hexadecimal digits: the number of data bytes, their 16-bit address, the
+// record type, the data bytes, and a checksum, which makes the sum of all the bytes of the record 0. These types are
+// understood:
+//
+//	00  data
+//	01  end of file
+//	02  extended segment address, adding its value times 16 to the following addresses
+//	03  start segment address, ignored
+//	04  extended linear address, adding its value times 65536 to the following addresses
+//	05  start linear address, ignored
+//
+// As the 8080 addresses 64K of memory, the data placed beyond it by extended addresses is reported as an error.
+package ihex
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/miguelff/8080/obj"
+)
+
+// Record types
+const (
+	Data = iota
+	EOF
+	ExtendedSegmentAddress
+	StartSegmentAddress
+	ExtendedLinearAddress
+	StartLinearAddress
+)
+
+// RecordSize is the maximum number of data bytes written per record
+const RecordSize = 16
+
+// Segment is a run of contiguous bytes of a program, as assembled and linked
+type Segment = obj.Segment
+
+// Read parses an Intel HEX file, returning its data as segments of contiguous bytes in ascending order of address.
+// Lines not starting with a colon are ignored, and so is everything after the end of file record.
+func Read(r io.Reader) ([]Segment, error) {
+	var segments []Segment
+	base := 0
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if !strings.HasPrefix(line, ":") {
+			continue
+		}
+		typ, addr, data, err := parseRecord(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+
+		switch typ {
+		case Data:
+			if len(data) == 0 {
+				continue
+			}
+			start := base + int(addr)
+			if start+len(data) > 0x10000 {
+				return nil, fmt.Errorf("line %d: data at %X beyond the 64K addressed by the 8080", n, start)
+			}
+			segments = append(segments, Segment{Addr: uint16(start), Data: data})
+		case EOF:
+			return merge(segments)
+		case ExtendedSegmentAddress, ExtendedLinearAddress:
+			if len(data) != 2 {
+				return nil, fmt.Errorf("line %d: extended address record with %d bytes, want 2", n, len(data))
+			}
+			base = int(data[0])<<8 | int(data[1])
+			if typ == ExtendedSegmentAddress {
+				base <<= 4
+			} else {
+				base <<= 16
+			}
+		case StartSegmentAddress, StartLinearAddress:
+		default:
+			return nil, fmt.Errorf("line %d: unknown record type %02X", n, typ)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("missing end of file record")
+}
+
+// parseRecord parses the hexadecimal digits of a record, following its colon
+func parseRecord(digits string) (typ byte, addr uint16, data []byte, err error) {
+	b, err := hex.DecodeString(digits)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("invalid record: %v", err)
+	}
+	if len(b) < 5 || len(b) != 5+int(b[0]) {
+		return 0, 0, nil, fmt.Errorf("invalid record length")
+	}
+	if sum := checksum(b[:len(b)-1]); sum != b[len(b)-1] {
+		return 0, 0, nil, fmt.Errorf("checksum %02X, want %02X", b[len(b)-1], sum)
+	}
+	return b[3], uint16(b[1])<<8 | uint16(b[2]), b[4 : len(b)-1], nil
+}
+
+// checksum returns the two's complement of the sum of the bytes
+func checksum(b []byte) byte {
+	var sum byte
+	for _, v := range b {
+		sum += v
+	}
+	return -sum
+}
+
+// merge sorts the segments by address, joining the contiguous ones, and fails if any overlap
+func merge(segments []Segment) ([]Segment, error) {
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].Addr < segments[j].Addr })
+	var merged []Segment
+	for _, s := range segments {
+		if len(merged) == 0 {
+			merged = append(merged, s)
+			continue
+		}
+		last := &merged[len(merged)-1]
+		end := int(last.Addr) + len(last.Data)
+		switch {
+		case int(s.Addr) < end:
+			return nil, fmt.Errorf("data at %04X overlaps the data at %04X-%04X", s.Addr, last.Addr, end-1)
+		case int(s.Addr) == end:
+			last.Data = append(last.Data[:len(last.Data):len(last.Data)], s.Data...)
+		default:
+			merged = append(merged, s)
+		}
+	}
+	return merged, nil
+}
+
+// Write writes the segments as data records of up to RecordSize bytes, followed by the end of file record
+func Write(w io.Writer, segments []Segment) error {
+	bw := bufio.NewWriter(w)
+	for _, s := range segments {
+		if int(s.Addr)+len(s.Data) > 0x10000 {
+			return fmt.Errorf("segment at %04X of %d bytes beyond the 64K addressed by the 8080", s.Addr, len(s.Data))
+		}
+		for i := 0; i < len(s.Data); i += RecordSize {
+			end := i + RecordSize
+			if end > len(s.Data) {
+				end = len(s.Data)
+			}
+			writeRecord(bw, Data, s.Addr+uint16(i), s.Data[i:end])
+		}
+	}
+	writeRecord(bw, EOF, 0, nil)
+	return bw.Flush()
+}
+
+func writeRecord(w io.Writer, typ byte, addr uint16, data []byte) {
+	b := append([]byte{byte(len(data)), byte(addr >> 8), byte(addr), typ}, data...)
+	b = append(b, checksum(b))
+	fmt.Fprintf(w, ":%s\n", strings.ToUpper(hex.EncodeToString(b)))
+}
@@ -0,0 +1,98 @@
+package ihex
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miguelff/8080/encoding"
+)
+
+func TestRead(t *testing.T) {
+	for _, tC := range []struct {
+		desc    string
+		file    string
+		want    []Segment
+		wantErr string
+	}{
+		{
+			"data",
+			":0300300002337A1E\r\n:00000001FF\r\n",
+			[]Segment{{Addr: 0x30, Data: encoding.MustParseHex("02 33 7A")}},
+			"",
+		},
+		{
+			"contiguous records are merged, sparse ones are not",
+			":020102000304F4\n:0201000001 02FA\n:0110000076 79\n:00000001FF\n",
+			[]Segment{
+				{Addr: 0x100, Data: encoding.MustParseHex("01 02 03 04")},
+				{Addr: 0x1000, Data: encoding.MustParseHex("76")},
+			},
+			"",
+		},
+		{
+			"extended and start addresses",
+			":020000020000FC\n:020000040000FA\n:0400000300000100F8\n:0100010076 88\n:00000001FF\n:0100020076 87\n",
+			[]Segment{{Addr: 0x0001, Data: encoding.MustParseHex("76")}},
+			"",
+		},
+		{
+			"extended segment address",
+			":020000020100FB\n:0100000076 89\n:00000001FF\n",
+			[]Segment{{Addr: 0x1000, Data: encoding.MustParseHex("76")}},
+			"",
+		},
+		{"bad checksum", ":0100000076 88\n:00000001FF\n", nil, "line 1: checksum 88, want 89"},
+		{"bad length", ":0200000076 88\n", nil, "line 1: invalid record length"},
+		{"bad digits", ":01000000XX00\n", nil, "line 1: invalid record: encoding/hex: invalid byte: U+0058 'X'"},
+		{"unknown type", "\n:0000000BF5\n", nil, "line 2: unknown record type 0B"},
+		{"missing end of file", ":0100000076 89\n", nil, "missing end of file record"},
+		{"beyond 64K", ":020000040001F9\n:0100000076 89\n:00000001FF\n", nil, "line 2: data at 10000 beyond the 64K addressed by the 8080"},
+		{"overlap", ":020000000102FB\n:0100010076 88\n:00000001FF\n", nil, "data at 0001 overlaps the data at 0000-0001"},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			got, err := Read(strings.NewReader(strings.ReplaceAll(tC.file, " ", "")))
+			if tC.wantErr != "" {
+				if err == nil || err.Error() != tC.wantErr {
+					t.Errorf("got error %v, want %q", err, tC.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tC.want) {
+				t.Errorf("got %+v, want %+v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	data := make([]byte, 20)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	segments := []Segment{{Addr: 0x100, Data: data}, {Addr: 0x2000, Data: encoding.MustParseHex("76")}}
+
+	var b bytes.Buffer
+	if err := Write(&b, segments); err != nil {
+		t.Fatal(err)
+	}
+	want := ":10010000000102030405060708090A0B0C0D0E0F77\n" +
+		":0401100010111213A5\n" +
+		":0120000076 69\n" +
+		":00000001FF\n"
+	if got := b.String(); got != strings.ReplaceAll(want, " ", "") {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	got, err := Read(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, segments) {
+		t.Errorf("read back %+v, want %+v", got, segments)
+	}
+}
@@ -0,0 +1,553 @@
+// gdbstub serves the GDB remote serial protocol (RSP) for an emulated computer, so debuggers speaking it can inspect
+// and drive the programs it runs.
+//
+// The registers are exposed as six 16-bit little-endian values, in this order: AF, BC, DE, HL, SP and PC. This is the
+// same layout GDB uses for the first registers of the Z80, so front ends configured for it work out of the box.
+package gdbstub
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/miguelff/8080/emu"
+)
+
+const (
+	regAF = iota
+	regBC
+	regDE
+	regHL
+	regSP
+	regPC
+	numRegs
+)
+
+// flagsMask keeps the bits of F that are meaningful. Bit 1 always reads as 1, while bits 3 and 5 always read as 0.
+const flagsMask = 0xD5
+
+// packetSize is the maximum size of the packets we are willing to receive
+const packetSize = 0x4000
+
+// interruptByte is sent by the debugger to stop a running program
+const interruptByte = 0x03
+
+// Signal numbers reported in stop replies
+const (
+	sigInt  = 0x02
+	sigIll  = 0x04
+	sigTrap = 0x05
+)
+
+const targetXML = `<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+  <feature name="org.gnu.gdb.i8080.core">
+    <reg name="af" bitsize="16" type="int" regnum="0"/>
+    <reg name="bc" bitsize="16" type="int"/>
+    <reg name="de" bitsize="16" type="int"/>
+    <reg name="hl" bitsize="16" type="int"/>
+    <reg name="sp" bitsize="16" type="data_ptr"/>
+    <reg name="pc" bitsize="16" type="code_ptr"/>
+  </feature>
+</target>
+`
+
+// Stub serves the remote protocol for a computer. Breakpoints are kept across debugging sessions.
+type Stub struct {
+	c  *emu.Computer
+	bp *emu.Breakpoints
+
+	// lastStop is the stop reply describing the state of the computer
+	lastStop string
+}
+
+// New creates a stub serving the protocol for the given computer
+func New(c *emu.Computer) *Stub {
+	return &Stub{
+		c:        c,
+		bp:       emu.NewBreakpoints(),
+		lastStop: stopReply(sigTrap),
+	}
+}
+
+// ListenAndServe listens on the given local TCP address, for instance "localhost:1234", and serves the debugging
+// sessions of the clients connecting to it, one after the other. It only returns when the listener fails.
+func (s *Stub) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		_ = s.Serve(conn)
+		_ = conn.Close()
+	}
+}
+
+// packet is a command received from the debugger
+type packet struct {
+	data string
+	// valid is false when the checksum didn't match
+	valid bool
+}
+
+// session holds the state of the connection with a debugger
+type session struct {
+	*Stub
+	w       *bufio.Writer
+	packets chan packet
+	// quit is closed when the session is over
+	quit chan struct{}
+	// interrupted is set to 1 when the debugger asks to stop the running program
+	interrupted int32
+	noAck       bool
+}
+
+// Serve runs a debugging session on the given connection. It returns when the debugger detaches, kills the program,
+// or the connection is closed.
+func (s *Stub) Serve(conn io.ReadWriter) error {
+	ss := &session{
+		Stub:    s,
+		w:       bufio.NewWriter(conn),
+		packets: make(chan packet),
+		quit:    make(chan struct{}),
+	}
+	defer close(ss.quit)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- ss.read(bufio.NewReader(conn))
+		close(ss.packets)
+	}()
+
+	for p := range ss.packets {
+		if !ss.noAck {
+			ack := byte('+')
+			if !p.valid {
+				ack = '-'
+			}
+			if err := ss.w.WriteByte(ack); err != nil {
+				return err
+			}
+		}
+		if !p.valid {
+			if err := ss.w.Flush(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		reply, done := ss.handle(p.data)
+		if reply != nil {
+			if err := ss.send(*reply); err != nil {
+				return err
+			}
+		}
+		if err := ss.w.Flush(); err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+
+	if err := <-errs; err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// read parses the packets sent by the debugger, and flags the interruption requests
+func (ss *session) read(r *bufio.Reader) error {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		switch b {
+		case interruptByte:
+			atomic.StoreInt32(&ss.interrupted, 1)
+		case '$':
+			data, err := r.ReadString('#')
+			if err != nil {
+				return err
+			}
+			data = data[:len(data)-1]
+
+			sum := make([]byte, 2)
+			if _, err := io.ReadFull(r, sum); err != nil {
+				return err
+			}
+			want, err := strconv.ParseUint(string(sum), 16, 8)
+			select {
+			case ss.packets <- packet{data: data, valid: err == nil && byte(want) == checksum(data)}:
+			case <-ss.quit:
+				return nil
+			}
+		}
+		// anything else, like acknowledgments, is ignored
+	}
+}
+
+// send writes a packet with the given data
+func (ss *session) send(data string) error {
+	_, err := fmt.Fprintf(ss.w, "$%s#%02x", escape(data), checksum(data))
+	return err
+}
+
+func checksum(data string) byte {
+	var sum byte
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum
+}
+
+// escape escapes the characters with special meaning in the protocol
+func escape(data string) string {
+	if !strings.ContainsAny(data, "$#}*") {
+		return data
+	}
+	var sb strings.Builder
+	for i := 0; i < len(data); i++ {
+		switch b := data[i]; b {
+		case '$', '#', '}', '*':
+			sb.WriteByte('}')
+			sb.WriteByte(b ^ 0x20)
+		default:
+			sb.WriteByte(b)
+		}
+	}
+	return sb.String()
+}
+
+func reply(s string) *string {
+	return &s
+}
+
+var (
+	replyOK          = reply("OK")
+	replyUnsupported = reply("")
+)
+
+// replyErr builds an error reply with the given errno
+func replyErr(errno int) *string {
+	return reply(fmt.Sprintf("E%02x", errno))
+}
+
+// handle executes the given command, and returns the reply to send, if any, and whether the session is over
+func (ss *session) handle(cmd string) (*string, bool) {
+	if cmd == "" {
+		return replyUnsupported, false
+	}
+
+	args := cmd[1:]
+	switch cmd[0] {
+	case '?':
+		return reply(ss.lastStop), false
+	case 'g':
+		return reply(ss.readRegisters()), false
+	case 'G':
+		return ss.writeRegisters(args), false
+	case 'p':
+		return ss.readRegister(args), false
+	case 'P':
+		return ss.writeRegister(args), false
+	case 'm':
+		return ss.readMemory(args), false
+	case 'M':
+		return ss.writeMemory(args), false
+	case 's':
+		return ss.resume(args, true), false
+	case 'c':
+		return ss.resume(args, false), false
+	case 'Z', 'z':
+		return ss.breakpoint(cmd[0] == 'Z', args), false
+	case 'H':
+		return replyOK, false
+	case 'D':
+		return replyOK, true
+	case 'k':
+		return nil, true
+	case 'q', 'Q':
+		return ss.query(cmd), false
+	}
+	return replyUnsupported, false
+}
+
+func (ss *session) query(cmd string) *string {
+	switch {
+	case strings.HasPrefix(cmd, "qSupported"):
+		return reply(fmt.Sprintf("PacketSize=%x;qXfer:features:read+;QStartNoAckMode+", packetSize))
+	case cmd == "QStartNoAckMode":
+		ss.noAck = true
+		return replyOK
+	case cmd == "qAttached":
+		return reply("1")
+	case cmd == "qC":
+		return reply("QC1")
+	case cmd == "qfThreadInfo":
+		return reply("m1")
+	case cmd == "qsThreadInfo":
+		return reply("l")
+	case strings.HasPrefix(cmd, "qXfer:features:read:target.xml:"):
+		return xfer(targetXML, strings.TrimPrefix(cmd, "qXfer:features:read:target.xml:"))
+	}
+	return replyUnsupported
+}
+
+// xfer replies with the chunk of the given document requested by a qXfer command
+func xfer(doc string, args string) *string {
+	offset, length, ok := parseRange(args)
+	if !ok {
+		return replyErr(0)
+	}
+	if offset >= len(doc) {
+		return reply("l")
+	}
+	if end := offset + length; end < len(doc) {
+		return reply("m" + doc[offset:end])
+	}
+	return reply("l" + doc[offset:])
+}
+
+// parseRange parses the "addr,length" arguments of several commands
+func parseRange(args string) (int, int, bool) {
+	i := strings.IndexByte(args, ',')
+	if i < 0 {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseUint(args[:i], 16, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	length, err := strconv.ParseUint(args[i+1:], 16, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	return int(start), int(length), true
+}
+
+func (ss *session) register(n int) uint16 {
+	c := ss.c
+	switch n {
+	case regAF:
+		return uint16(c.A)<<8 | uint16(byte(c.Flags)&flagsMask|0x02)
+	case regBC:
+		return c.BC()
+	case regDE:
+		return c.DE()
+	case regHL:
+		return c.HL()
+	case regSP:
+		return c.SP
+	default:
+		return c.PC
+	}
+}
+
+func (ss *session) setRegister(n int, v uint16) {
+	c := ss.c
+	hi, lo := byte(v>>8), byte(v)
+	switch n {
+	case regAF:
+		c.A, c.Flags = hi, emu.Flags(lo&flagsMask)
+	case regBC:
+		c.B, c.C = hi, lo
+	case regDE:
+		c.D, c.E = hi, lo
+	case regHL:
+		c.H, c.L = hi, lo
+	case regSP:
+		c.SP = v
+	case regPC:
+		c.PC = v
+	}
+}
+
+// encode16 encodes a register the way the protocol expects it: in target byte order
+func encode16(v uint16) string {
+	return hex.EncodeToString([]byte{byte(v), byte(v >> 8)})
+}
+
+func decode16(s string) (uint16, bool) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 2 {
+		return 0, false
+	}
+	return uint16(b[1])<<8 | uint16(b[0]), true
+}
+
+func (ss *session) readRegisters() string {
+	var sb strings.Builder
+	for n := 0; n < numRegs; n++ {
+		sb.WriteString(encode16(ss.register(n)))
+	}
+	return sb.String()
+}
+
+func (ss *session) writeRegisters(args string) *string {
+	if len(args) < numRegs*4 {
+		return replyErr(0x16)
+	}
+	values := make([]uint16, numRegs)
+	for n := range values {
+		v, ok := decode16(args[n*4 : n*4+4])
+		if !ok {
+			return replyErr(0x16)
+		}
+		values[n] = v
+	}
+	for n, v := range values {
+		ss.setRegister(n, v)
+	}
+	return replyOK
+}
+
+func (ss *session) readRegister(args string) *string {
+	n, err := strconv.ParseUint(args, 16, 8)
+	if err != nil || n >= numRegs {
+		return replyErr(0x16)
+	}
+	return reply(encode16(ss.register(int(n))))
+}
+
+func (ss *session) writeRegister(args string) *string {
+	i := strings.IndexByte(args, '=')
+	if i < 0 {
+		return replyErr(0x16)
+	}
+	n, err := strconv.ParseUint(args[:i], 16, 8)
+	if err != nil || n >= numRegs {
+		return replyErr(0x16)
+	}
+	v, ok := decode16(args[i+1:])
+	if !ok {
+		return replyErr(0x16)
+	}
+	ss.setRegister(int(n), v)
+	return replyOK
+}
+
+func (ss *session) readMemory(args string) *string {
+	addr, length, ok := parseRange(args)
+	if !ok {
+		return replyErr(0x16)
+	}
+	mem := ss.c.Mem
+	if addr >= len(mem) {
+		return replyErr(0x0e)
+	}
+	end := addr + length
+	if end > len(mem) {
+		end = len(mem)
+	}
+	return reply(hex.EncodeToString(mem[addr:end]))
+}
+
+func (ss *session) writeMemory(args string) *string {
+	i := strings.IndexByte(args, ':')
+	if i < 0 {
+		return replyErr(0x16)
+	}
+	addr, length, ok := parseRange(args[:i])
+	if !ok {
+		return replyErr(0x16)
+	}
+	data, err := hex.DecodeString(args[i+1:])
+	if err != nil || len(data) != length {
+		return replyErr(0x16)
+	}
+	if addr+length > len(ss.c.Mem) {
+		return replyErr(0x0e)
+	}
+	copy(ss.c.Mem[addr:], data)
+	return replyOK
+}
+
+// breakpoint inserts or removes a breakpoint or watchpoint. Both software (Z0) and hardware (Z1) breakpoints are
+// supported and behave the same, as they never patch memory. Watchpoints can only be set on writes (Z2).
+func (ss *session) breakpoint(insert bool, args string) *string {
+	parts := strings.Split(args, ",")
+	if len(parts) < 3 {
+		return replyErr(0x16)
+	}
+	addr, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return replyErr(0x16)
+	}
+	length, err := strconv.ParseUint(parts[2], 16, 16)
+	if err != nil {
+		return replyErr(0x16)
+	}
+
+	switch parts[0] {
+	case "0", "1":
+		if insert {
+			ss.bp.Break(uint16(addr))
+		} else {
+			ss.bp.Clear(uint16(addr))
+		}
+	case "2":
+		if length == 0 {
+			length = 1
+		}
+		for a := addr; a < addr+length; a++ {
+			if insert {
+				ss.bp.Watch(uint16(a))
+			} else {
+				ss.bp.Unwatch(uint16(a))
+			}
+		}
+	default:
+		return replyUnsupported
+	}
+	return replyOK
+}
+
+// resume steps or continues the execution of the program, optionally at the given address, and returns the stop reply
+func (ss *session) resume(args string, step bool) *string {
+	if args != "" {
+		addr, err := strconv.ParseUint(args, 16, 16)
+		if err != nil {
+			return replyErr(0x16)
+		}
+		ss.c.PC = uint16(addr)
+	}
+
+	stop := func() bool { return step || atomic.LoadInt32(&ss.interrupted) != 0 }
+	st := ss.c.Run(ss.bp, stop)
+	interrupted := atomic.SwapInt32(&ss.interrupted, 0) != 0
+
+	switch st.Reason {
+	case emu.StopWatchpoint:
+		ss.lastStop = fmt.Sprintf("T%02xwatch:%04x;", sigTrap, st.Addr)
+	case emu.StopError:
+		ss.lastStop = stopReply(sigIll)
+	case emu.StopRequested:
+		if interrupted && !step {
+			ss.lastStop = stopReply(sigInt)
+		} else {
+			ss.lastStop = stopReply(sigTrap)
+		}
+	default:
+		ss.lastStop = stopReply(sigTrap)
+	}
+	return reply(ss.lastStop)
+}
+
+func stopReply(signal int) string {
+	return fmt.Sprintf("S%02x", signal)
+}
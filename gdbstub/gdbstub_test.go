@@ -0,0 +1,115 @@
+package gdbstub
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/miguelff/8080/emu"
+	"github.com/miguelff/8080/encoding"
+)
+
+// client talks to a stub the way a debugger would
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func newClient(t *testing.T, c *emu.Computer) (*client, chan error) {
+	server, conn := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- New(c).Serve(server)
+		server.Close()
+	}()
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}, done
+}
+
+// exchange sends a command and returns the reply to it
+func (cl *client) exchange(cmd string) string {
+	cl.t.Helper()
+	if _, err := fmt.Fprintf(cl.conn, "$%s#%02x", cmd, checksum(cmd)); err != nil {
+		cl.t.Fatalf("sending %q: %v", cmd, err)
+	}
+	if ack, err := cl.r.ReadByte(); err != nil || ack != '+' {
+		cl.t.Fatalf("sending %q: got ack %q (%v)", cmd, ack, err)
+	}
+	if b, err := cl.r.ReadByte(); err != nil || b != '$' {
+		cl.t.Fatalf("reading reply to %q: got %q (%v)", cmd, b, err)
+	}
+	data, err := cl.r.ReadString('#')
+	if err != nil {
+		cl.t.Fatalf("reading reply to %q: %v", cmd, err)
+	}
+	if _, err := cl.r.Discard(2); err != nil {
+		cl.t.Fatalf("reading reply to %q: %v", cmd, err)
+	}
+	return data[:len(data)-1]
+}
+
+func TestStub(t *testing.T) {
+	// 0000 MVI A,$42 ; 0002 STA $0020 ; 0005 INR B ; 0006 JMP $0005
+	c := emu.Load(encoding.MustParseHex("3E 42 32 20 00 04 C3 05 00"))
+	cl, done := newClient(t, c)
+
+	for _, tC := range []struct {
+		cmd  string
+		want string
+	}{
+		{"?", "S05"},
+		{"g", "020000000000000000000000"},
+		{"P0=0042", "OK"},
+		{"p0", "0242"},
+		{"m0,3", "3e4232"},
+		{"m4000,1", "E0e"},
+		{"M30,2:beef", "OK"},
+		{"m30,2", "beef"},
+		{"s", "S05"},
+		{"p5", "0200"},
+		{"Z2,20,1", "OK"},
+		{"c", "T05watch:0020;"},
+		{"m20,1", "42"},
+		{"Z0,6,1", "OK"},
+		{"c", "S05"},
+		{"p5", "0600"},
+		{"z0,6,1", "OK"},
+		{"Z1,5,1", "OK"},
+		{"c", "S05"},
+		{"p5", "0500"},
+		{"p1", "0001"},
+		{"Z3,20,1", ""},
+		{"vMustReplyEmpty", ""},
+		{"D", "OK"},
+	} {
+		if got := cl.exchange(tC.cmd); got != tC.want {
+			t.Errorf("%s: got %q, want %q", tC.cmd, got, tC.want)
+		}
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error serving the session: %v", err)
+	}
+}
+
+func TestStub_Interrupt(t *testing.T) {
+	// 0000 JMP $0000
+	c := emu.Load(encoding.MustParseHex("C3 00 00"))
+	cl, done := newClient(t, c)
+	defer cl.conn.Close()
+
+	replies := make(chan string)
+	go func() {
+		replies <- cl.exchange("c")
+	}()
+	if _, err := cl.conn.Write([]byte{interruptByte}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-replies; got != "S02" {
+		t.Errorf("got %q, want %q", got, "S02")
+	}
+
+	cl.conn.Close()
+	<-done
+}
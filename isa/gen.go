@@ -0,0 +1,166 @@
+//go:build ignore
+// +build ignore
+
+// gen generates table.go from the instruction set described in opcodes.txt
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	flagNames  = map[string]string{"Z": "Z", "S": "S", "P": "P", "CY": "CY", "AC": "AC"}
+	classNames = map[string]string{
+		"transfer":   "Transfer",
+		"arithmetic": "Arithmetic",
+		"logical":    "Logical",
+		"branch":     "Branch",
+		"control":    "Control",
+	}
+)
+
+func main() {
+	f, err := os.Open("opcodes.txt")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by go run gen.go; DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "package isa")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "// Opcodes are the instructions assigned to each opcode, as described in opcodes.txt")
+	fmt.Fprintln(&buf, "var Opcodes = [256]Opcode{")
+
+	seen := make(map[int]bool)
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry, op, err := parse(line)
+		if err != nil {
+			log.Fatalf("opcodes.txt:%d: %v", n, err)
+		}
+		if seen[op] {
+			log.Fatalf("opcodes.txt:%d: opcode 0x%02X described twice", n, op)
+		}
+		seen[op] = true
+		fmt.Fprintf(&buf, "\t0x%02X: %s,\n", op, entry)
+	}
+	if err := s.Err(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintln(&buf, "}")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("table.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parse parses a line of opcodes.txt, returning the opcode it describes and the Go literal of its entry
+func parse(line string) (string, int, error) {
+	cols := strings.Split(line, "\t")
+	if len(cols) != 7 {
+		return "", 0, fmt.Errorf("got %d columns, want 7", len(cols))
+	}
+	op, err := strconv.ParseUint(cols[0], 0, 8)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid opcode %q", cols[0])
+	}
+
+	var fields []string
+	field := func(name string, value interface{}) {
+		fields = append(fields, fmt.Sprintf("%s: %v", name, value))
+	}
+
+	inst := strings.Fields(cols[1])
+	field("Mnemonic", strconv.Quote(inst[0]))
+	var regs []string
+	if len(inst) > 1 {
+		regs = strings.Split(inst[1], ",")
+	}
+	operand := ""
+	if len(regs) > 0 {
+		switch last := regs[len(regs)-1]; {
+		case last == "D8" && (inst[0] == "IN" || inst[0] == "OUT"):
+			operand = "Port"
+		case last == "D8":
+			operand = "Immediate8"
+		case last == "D16":
+			operand = "Immediate16"
+		case last == "adr":
+			operand = "Address"
+		case inst[0] == "RST":
+			operand = "Vector"
+		}
+	}
+	if operand != "" {
+		regs = regs[:len(regs)-1]
+	}
+	if len(regs) > 0 {
+		field("Regs", strconv.Quote(strings.Join(regs, ",")))
+	}
+	if operand != "" {
+		field("Operand", operand)
+	}
+	if operand == "Vector" {
+		field("Vector", inst[1])
+	}
+
+	size, err := strconv.Atoi(cols[2])
+	if err != nil || size < 1 || size > 3 {
+		return "", 0, fmt.Errorf("invalid size %q", cols[2])
+	}
+	field("Size", size)
+
+	taken, notTaken := cols[3], cols[3]
+	if i := strings.Index(cols[3], "/"); i >= 0 {
+		taken, notTaken = cols[3][:i], cols[3][i+1:]
+	}
+	for _, c := range []struct {
+		name, value string
+	}{{"Cycles", taken}, {"CyclesNotTaken", notTaken}} {
+		n, err := strconv.Atoi(c.value)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid cycles %q", cols[3])
+		}
+		field(c.name, n)
+	}
+
+	if cols[4] != "-" {
+		var flags []string
+		for _, name := range strings.Split(cols[4], ",") {
+			flag, ok := flagNames[name]
+			if !ok {
+				return "", 0, fmt.Errorf("invalid flag %q", name)
+			}
+			flags = append(flags, flag)
+		}
+		field("Flags", strings.Join(flags, " | "))
+	}
+
+	class, ok := classNames[cols[5]]
+	if !ok {
+		return "", 0, fmt.Errorf("invalid class %q", cols[5])
+	}
+	field("Class", class)
+	field("Description", strconv.Quote(cols[6]))
+
+	return "{" + strings.Join(fields, ", ") + "}", int(op), nil
+}
@@ -0,0 +1,148 @@
+// Package isa describes the instruction set of the Intel 8080: the instruction assigned to each opcode, its operands,
+// size, duration, the flags it affects and its class.
+//
+// Opcodes is the single table the disassembler, the assembler and the emulator derive their knowledge of the opcodes
+// from. It's generated from opcodes.txt, which is the file to edit, by running go generate.
+package isa
+
+import "strings"
+
+//go:generate go run gen.go
+
+// Operand tells what follows the opcode of an instruction, or is encoded in it
+type Operand int
+
+const (
+	// NoOperand instructions have only register operands, if any
+	NoOperand Operand = iota
+	// Immediate8 is an 8-bit value (D8), as in MVI B,D8
+	Immediate8
+	// Port is an 8-bit port number, as in IN D8
+	Port
+	// Immediate16 is a 16-bit value (D16), as in LXI B,D16
+	Immediate16
+	// Address is a 16-bit memory address (adr), as in LDA adr or JMP adr
+	Address
+	// Vector is the number of a restart, encoded in the opcode of RST
+	Vector
+)
+
+// Flags is a set of condition flags
+type Flags byte
+
+const (
+	// Z is the zero flag
+	Z Flags = 1 << iota
+	// S is the sign flag
+	S
+	// P is the parity flag
+	P
+	// CY is the carry flag
+	CY
+	// AC is the auxiliary carry flag
+	AC
+)
+
+var flagNames = []string{"Z", "S", "P", "CY", "AC"}
+
+// String lists the flags in the set, like "Z,S,P,AC", or returns "-" when it's empty
+func (f Flags) String() string {
+	var names []string
+	for i, name := range flagNames {
+		if f&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ",")
+}
+
+// Class is the group of instructions an instruction belongs to in the 8080 manual
+type Class int
+
+const (
+	// Undefined is the class of the opcodes with no instruction assigned
+	Undefined Class = iota
+	// Transfer instructions move data between registers and memory: MOV, MVI, LXI, LDA, XCHG...
+	Transfer
+	// Arithmetic instructions add, subtract, increment and decrement: ADD, SUB, INR, DAD, DAA...
+	Arithmetic
+	// Logical instructions operate on bits, compare and rotate the accumulator, and set the carry: ANA, CMP, RLC, STC...
+	Logical
+	// Branch instructions transfer control: JMP, CALL, RET, RST, PCHL and their conditional forms
+	Branch
+	// Control instructions are the stack, I/O and machine control ones: PUSH, POP, IN, OUT, EI, HLT, NOP...
+	Control
+)
+
+var classNames = []string{"undefined", "transfer", "arithmetic", "logical", "branch", "control"}
+
+// String returns the name of the class, like "arithmetic"
+func (c Class) String() string {
+	if c < 0 || int(c) >= len(classNames) {
+		return "undefined"
+	}
+	return classNames[c]
+}
+
+// Opcode describes the instruction assigned to an opcode
+type Opcode struct {
+	// Mnemonic is the name of the instruction, like "MOV"
+	Mnemonic string
+	// Regs are the register operands, like "B,C" for MOV B,C, or "SP" for LXI SP,D16
+	Regs string
+	// Operand is the kind of the operand following the opcode, or encoded in it for RST
+	Operand Operand
+	// Vector is the number of the restart of RST
+	Vector int
+	// Size is the number of bytes of the instruction, including its opcode
+	Size int
+	// Cycles is the number of states the instruction takes, when its condition holds for the conditional ones
+	Cycles int
+	// CyclesNotTaken is the number of states taken by conditional calls and returns when their condition doesn't
+	// hold. It's Cycles for the rest of instructions.
+	CyclesNotTaken int
+	// Flags are the flags the instruction changes
+	Flags Flags
+	// Class is the group the instruction belongs to, or Undefined for the opcodes with no instruction assigned
+	Class Class
+	// Description tells what the instruction does, like "A <- A + B + CY"
+	Description string
+}
+
+// Defined tells whether an instruction is assigned to the opcode
+func (o Opcode) Defined() bool {
+	return o.Class != Undefined
+}
+
+// String returns the instruction as written in the 8080 manual, like "MVI B,D8", "JMP adr" or "RST 7"
+func (o Opcode) String() string {
+	if !o.Defined() {
+		return "-"
+	}
+	operands := o.Regs
+	switch o.Operand {
+	case Immediate8, Port:
+		operands = join(operands, "D8")
+	case Immediate16:
+		operands = join(operands, "D16")
+	case Address:
+		operands = join(operands, "adr")
+	case Vector:
+		operands = string(rune('0' + o.Vector))
+	}
+	if operands == "" {
+		return o.Mnemonic
+	}
+	return o.Mnemonic + " " + operands
+}
+
+// join joins the register operands and the one following them
+func join(regs, operand string) string {
+	if regs == "" {
+		return operand
+	}
+	return regs + "," + operand
+}
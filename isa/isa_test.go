@@ -0,0 +1,124 @@
+package isa
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestOpcode_String(t *testing.T) {
+	for _, tC := range []struct {
+		desc string
+		op   byte
+		want string
+	}{
+		{"no operands", 0x00, "NOP"},
+		{"registers", 0x41, "MOV B,C"},
+		{"register pair", 0xF5, "PUSH PSW"},
+		{"immediate", 0x06, "MVI B,D8"},
+		{"immediate without registers", 0xC6, "ADI D8"},
+		{"port", 0xDB, "IN D8"},
+		{"16-bit immediate", 0x31, "LXI SP,D16"},
+		{"address", 0xC3, "JMP adr"},
+		{"vector", 0xFF, "RST 7"},
+		{"undefined", 0xCB, "-"},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := Opcodes[tC.op].String(); got != tC.want {
+				t.Errorf("got %q, want %q", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestFlags_String(t *testing.T) {
+	for _, tC := range []struct {
+		desc  string
+		flags Flags
+		want  string
+	}{
+		{"none", 0, "-"},
+		{"one", CY, "CY"},
+		{"some", Z | S | P | AC, "Z,S,P,AC"},
+		{"all", Z | S | P | CY | AC, "Z,S,P,CY,AC"},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := tC.flags.String(); got != tC.want {
+				t.Errorf("got %q, want %q", got, tC.want)
+			}
+		})
+	}
+}
+
+// TestOpcodes checks the table is consistent, and generated from the current opcodes.txt
+func TestOpcodes(t *testing.T) {
+	f, err := os.Open("opcodes.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var described [256]bool
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cols := strings.Split(line, "\t")
+		n, err := strconv.ParseUint(cols[0], 0, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		op := Opcodes[n]
+		described[n] = true
+
+		cycles := strconv.Itoa(op.Cycles)
+		if op.CyclesNotTaken != op.Cycles {
+			cycles += "/" + strconv.Itoa(op.CyclesNotTaken)
+		}
+		got := strings.Join([]string{
+			fmt.Sprintf("0x%02X", n), op.String(), strconv.Itoa(op.Size), cycles, op.Flags.String(), op.Class.String(),
+			op.Description,
+		}, "\t")
+		if got != line {
+			t.Errorf("got %q, want %q: run go generate", got, line)
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	defined := 0
+	mnemonics := make(map[string]bool)
+	for n, op := range Opcodes {
+		if !op.Defined() {
+			if described[n] || op != (Opcode{}) {
+				t.Errorf("0x%02X: got %+v for an undefined opcode", n, op)
+			}
+			continue
+		}
+		defined++
+		if !described[n] {
+			t.Errorf("0x%02X: %s isn't described in opcodes.txt: run go generate", n, op)
+		}
+		if mnemonics[op.String()] {
+			t.Errorf("0x%02X: %s is assigned to several opcodes", n, op)
+		}
+		mnemonics[op.String()] = true
+
+		size := map[Operand]int{NoOperand: 1, Immediate8: 2, Port: 2, Immediate16: 3, Address: 3, Vector: 1}[op.Operand]
+		if op.Size != size {
+			t.Errorf("0x%02X: %s has size %d, want %d", n, op, op.Size, size)
+		}
+		if op.Operand == Vector && byte(op.Vector<<3|0xC7) != byte(n) {
+			t.Errorf("0x%02X: %s has the wrong vector", n, op)
+		}
+	}
+	if defined != 244 {
+		t.Errorf("got %d opcodes defined, want 244", defined)
+	}
+}
@@ -0,0 +1,251 @@
+// Code generated by go run gen.go; DO NOT EDIT.
+
+package isa
+
+// Opcodes are the instructions assigned to each opcode, as described in opcodes.txt
+var Opcodes = [256]Opcode{
+	0x00: {Mnemonic: "NOP", Size: 1, Cycles: 4, CyclesNotTaken: 4, Class: Control, Description: "nothing"},
+	0x01: {Mnemonic: "LXI", Regs: "B", Operand: Immediate16, Size: 3, Cycles: 10, CyclesNotTaken: 10, Class: Transfer, Description: "B <- byte 3; C <- byte 2"},
+	0x02: {Mnemonic: "STAX", Regs: "B", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "(BC) <- A"},
+	0x03: {Mnemonic: "INX", Regs: "B", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Arithmetic, Description: "BC <- BC + 1"},
+	0x04: {Mnemonic: "INR", Regs: "B", Size: 1, Cycles: 5, CyclesNotTaken: 5, Flags: Z | S | P | AC, Class: Arithmetic, Description: "B <- B + 1"},
+	0x05: {Mnemonic: "DCR", Regs: "B", Size: 1, Cycles: 5, CyclesNotTaken: 5, Flags: Z | S | P | AC, Class: Arithmetic, Description: "B <- B - 1"},
+	0x06: {Mnemonic: "MVI", Regs: "B", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "B <- byte 2"},
+	0x07: {Mnemonic: "RLC", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: CY, Class: Logical, Description: "A <- A << 1; bit 0 <- prev bit 7; CY <- prev bit 7"},
+	0x09: {Mnemonic: "DAD", Regs: "B", Size: 1, Cycles: 10, CyclesNotTaken: 10, Flags: CY, Class: Arithmetic, Description: "HL <- HL + BC"},
+	0x0A: {Mnemonic: "LDAX", Regs: "B", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "A <- (BC)"},
+	0x0B: {Mnemonic: "DCX", Regs: "B", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Arithmetic, Description: "BC <- BC - 1"},
+	0x0C: {Mnemonic: "INR", Regs: "C", Size: 1, Cycles: 5, CyclesNotTaken: 5, Flags: Z | S | P | AC, Class: Arithmetic, Description: "C <- C + 1"},
+	0x0D: {Mnemonic: "DCR", Regs: "C", Size: 1, Cycles: 5, CyclesNotTaken: 5, Flags: Z | S | P | AC, Class: Arithmetic, Description: "C <- C - 1"},
+	0x0E: {Mnemonic: "MVI", Regs: "C", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "C <- byte 2"},
+	0x0F: {Mnemonic: "RRC", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: CY, Class: Logical, Description: "A <- A >> 1; bit 7 <- prev bit 0; CY <- prev bit 0"},
+	0x11: {Mnemonic: "LXI", Regs: "D", Operand: Immediate16, Size: 3, Cycles: 10, CyclesNotTaken: 10, Class: Transfer, Description: "D <- byte 3; E <- byte 2"},
+	0x12: {Mnemonic: "STAX", Regs: "D", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "(DE) <- A"},
+	0x13: {Mnemonic: "INX", Regs: "D", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Arithmetic, Description: "DE <- DE + 1"},
+	0x14: {Mnemonic: "INR", Regs: "D", Size: 1, Cycles: 5, CyclesNotTaken: 5, Flags: Z | S | P | AC, Class: Arithmetic, Description: "D <- D + 1"},
+	0x15: {Mnemonic: "DCR", Regs: "D", Size: 1, Cycles: 5, CyclesNotTaken: 5, Flags: Z | S | P | AC, Class: Arithmetic, Description: "D <- D - 1"},
+	0x16: {Mnemonic: "MVI", Regs: "D", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "D <- byte 2"},
+	0x17: {Mnemonic: "RAL", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: CY, Class: Logical, Description: "A <- A << 1; bit 0 <- prev CY; CY <- prev bit 7"},
+	0x19: {Mnemonic: "DAD", Regs: "D", Size: 1, Cycles: 10, CyclesNotTaken: 10, Flags: CY, Class: Arithmetic, Description: "HL <- HL + DE"},
+	0x1A: {Mnemonic: "LDAX", Regs: "D", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "A <- (DE)"},
+	0x1B: {Mnemonic: "DCX", Regs: "D", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Arithmetic, Description: "DE <- DE - 1"},
+	0x1C: {Mnemonic: "INR", Regs: "E", Size: 1, Cycles: 5, CyclesNotTaken: 5, Flags: Z | S | P | AC, Class: Arithmetic, Description: "E <- E + 1"},
+	0x1D: {Mnemonic: "DCR", Regs: "E", Size: 1, Cycles: 5, CyclesNotTaken: 5, Flags: Z | S | P | AC, Class: Arithmetic, Description: "E <- E - 1"},
+	0x1E: {Mnemonic: "MVI", Regs: "E", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "E <- byte 2"},
+	0x1F: {Mnemonic: "RAR", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: CY, Class: Logical, Description: "A <- A >> 1; bit 7 <- prev CY; CY <- prev bit 0"},
+	0x21: {Mnemonic: "LXI", Regs: "H", Operand: Immediate16, Size: 3, Cycles: 10, CyclesNotTaken: 10, Class: Transfer, Description: "H <- byte 3; L <- byte 2"},
+	0x22: {Mnemonic: "SHLD", Operand: Address, Size: 3, Cycles: 16, CyclesNotTaken: 16, Class: Transfer, Description: "(adr) <- L; (adr+1) <- H"},
+	0x23: {Mnemonic: "INX", Regs: "H", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Arithmetic, Description: "HL <- HL + 1"},
+	0x24: {Mnemonic: "INR", Regs: "H", Size: 1, Cycles: 5, CyclesNotTaken: 5, Flags: Z | S | P | AC, Class: Arithmetic, Description: "H <- H + 1"},
+	0x25: {Mnemonic: "DCR", Regs: "H", Size: 1, Cycles: 5, CyclesNotTaken: 5, Flags: Z | S | P | AC, Class: Arithmetic, Description: "H <- H - 1"},
+	0x26: {Mnemonic: "MVI", Regs: "H", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "H <- byte 2"},
+	0x27: {Mnemonic: "DAA", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A adjusted to 2 BCD digits"},
+	0x29: {Mnemonic: "DAD", Regs: "H", Size: 1, Cycles: 10, CyclesNotTaken: 10, Flags: CY, Class: Arithmetic, Description: "HL <- HL + HL"},
+	0x2A: {Mnemonic: "LHLD", Operand: Address, Size: 3, Cycles: 16, CyclesNotTaken: 16, Class: Transfer, Description: "L <- (adr); H <- (adr+1)"},
+	0x2B: {Mnemonic: "DCX", Regs: "H", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Arithmetic, Description: "HL <- HL - 1"},
+	0x2C: {Mnemonic: "INR", Regs: "L", Size: 1, Cycles: 5, CyclesNotTaken: 5, Flags: Z | S | P | AC, Class: Arithmetic, Description: "L <- L + 1"},
+	0x2D: {Mnemonic: "DCR", Regs: "L", Size: 1, Cycles: 5, CyclesNotTaken: 5, Flags: Z | S | P | AC, Class: Arithmetic, Description: "L <- L - 1"},
+	0x2E: {Mnemonic: "MVI", Regs: "L", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "L <- byte 2"},
+	0x2F: {Mnemonic: "CMA", Size: 1, Cycles: 4, CyclesNotTaken: 4, Class: Logical, Description: "A <- !A"},
+	0x31: {Mnemonic: "LXI", Regs: "SP", Operand: Immediate16, Size: 3, Cycles: 10, CyclesNotTaken: 10, Class: Transfer, Description: "SP <- D16"},
+	0x32: {Mnemonic: "STA", Operand: Address, Size: 3, Cycles: 13, CyclesNotTaken: 13, Class: Transfer, Description: "(adr) <- A"},
+	0x33: {Mnemonic: "INX", Regs: "SP", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Arithmetic, Description: "SP <- SP + 1"},
+	0x34: {Mnemonic: "INR", Regs: "M", Size: 1, Cycles: 10, CyclesNotTaken: 10, Flags: Z | S | P | AC, Class: Arithmetic, Description: "(HL) <- (HL) + 1"},
+	0x35: {Mnemonic: "DCR", Regs: "M", Size: 1, Cycles: 10, CyclesNotTaken: 10, Flags: Z | S | P | AC, Class: Arithmetic, Description: "(HL) <- (HL) - 1"},
+	0x36: {Mnemonic: "MVI", Regs: "M", Operand: Immediate8, Size: 2, Cycles: 10, CyclesNotTaken: 10, Class: Transfer, Description: "(HL) <- byte 2"},
+	0x37: {Mnemonic: "STC", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: CY, Class: Logical, Description: "CY <- 1"},
+	0x39: {Mnemonic: "DAD", Regs: "SP", Size: 1, Cycles: 10, CyclesNotTaken: 10, Flags: CY, Class: Arithmetic, Description: "HL <- HL + SP"},
+	0x3A: {Mnemonic: "LDA", Operand: Address, Size: 3, Cycles: 13, CyclesNotTaken: 13, Class: Transfer, Description: "A <- (adr)"},
+	0x3B: {Mnemonic: "DCX", Regs: "SP", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Arithmetic, Description: "SP <- SP - 1"},
+	0x3C: {Mnemonic: "INR", Regs: "A", Size: 1, Cycles: 5, CyclesNotTaken: 5, Flags: Z | S | P | AC, Class: Arithmetic, Description: "A <- A + 1"},
+	0x3D: {Mnemonic: "DCR", Regs: "A", Size: 1, Cycles: 5, CyclesNotTaken: 5, Flags: Z | S | P | AC, Class: Arithmetic, Description: "A <- A - 1"},
+	0x3E: {Mnemonic: "MVI", Regs: "A", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "A <- byte 2"},
+	0x3F: {Mnemonic: "CMC", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: CY, Class: Logical, Description: "CY <- !CY"},
+	0x40: {Mnemonic: "MOV", Regs: "B,B", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "B <- B"},
+	0x41: {Mnemonic: "MOV", Regs: "B,C", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "B <- C"},
+	0x42: {Mnemonic: "MOV", Regs: "B,D", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "B <- D"},
+	0x43: {Mnemonic: "MOV", Regs: "B,E", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "B <- E"},
+	0x44: {Mnemonic: "MOV", Regs: "B,H", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "B <- H"},
+	0x45: {Mnemonic: "MOV", Regs: "B,L", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "B <- L"},
+	0x46: {Mnemonic: "MOV", Regs: "B,M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "B <- (HL)"},
+	0x47: {Mnemonic: "MOV", Regs: "B,A", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "B <- A"},
+	0x48: {Mnemonic: "MOV", Regs: "C,B", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "C <- B"},
+	0x49: {Mnemonic: "MOV", Regs: "C,C", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "C <- C"},
+	0x4A: {Mnemonic: "MOV", Regs: "C,D", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "C <- D"},
+	0x4B: {Mnemonic: "MOV", Regs: "C,E", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "C <- E"},
+	0x4C: {Mnemonic: "MOV", Regs: "C,H", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "C <- H"},
+	0x4D: {Mnemonic: "MOV", Regs: "C,L", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "C <- L"},
+	0x4E: {Mnemonic: "MOV", Regs: "C,M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "C <- (HL)"},
+	0x4F: {Mnemonic: "MOV", Regs: "C,A", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "C <- A"},
+	0x50: {Mnemonic: "MOV", Regs: "D,B", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "D <- B"},
+	0x51: {Mnemonic: "MOV", Regs: "D,C", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "D <- C"},
+	0x52: {Mnemonic: "MOV", Regs: "D,D", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "D <- D"},
+	0x53: {Mnemonic: "MOV", Regs: "D,E", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "D <- E"},
+	0x54: {Mnemonic: "MOV", Regs: "D,H", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "D <- H"},
+	0x55: {Mnemonic: "MOV", Regs: "D,L", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "D <- L"},
+	0x56: {Mnemonic: "MOV", Regs: "D,M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "D <- (HL)"},
+	0x57: {Mnemonic: "MOV", Regs: "D,A", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "D <- A"},
+	0x58: {Mnemonic: "MOV", Regs: "E,B", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "E <- B"},
+	0x59: {Mnemonic: "MOV", Regs: "E,C", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "E <- C"},
+	0x5A: {Mnemonic: "MOV", Regs: "E,D", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "E <- D"},
+	0x5B: {Mnemonic: "MOV", Regs: "E,E", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "E <- E"},
+	0x5C: {Mnemonic: "MOV", Regs: "E,H", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "E <- H"},
+	0x5D: {Mnemonic: "MOV", Regs: "E,L", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "E <- L"},
+	0x5E: {Mnemonic: "MOV", Regs: "E,M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "E <- (HL)"},
+	0x5F: {Mnemonic: "MOV", Regs: "E,A", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "E <- A"},
+	0x60: {Mnemonic: "MOV", Regs: "H,B", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "H <- B"},
+	0x61: {Mnemonic: "MOV", Regs: "H,C", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "H <- C"},
+	0x62: {Mnemonic: "MOV", Regs: "H,D", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "H <- D"},
+	0x63: {Mnemonic: "MOV", Regs: "H,E", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "H <- E"},
+	0x64: {Mnemonic: "MOV", Regs: "H,H", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "H <- H"},
+	0x65: {Mnemonic: "MOV", Regs: "H,L", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "H <- L"},
+	0x66: {Mnemonic: "MOV", Regs: "H,M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "H <- (HL)"},
+	0x67: {Mnemonic: "MOV", Regs: "H,A", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "H <- A"},
+	0x68: {Mnemonic: "MOV", Regs: "L,B", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "L <- B"},
+	0x69: {Mnemonic: "MOV", Regs: "L,C", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "L <- C"},
+	0x6A: {Mnemonic: "MOV", Regs: "L,D", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "L <- D"},
+	0x6B: {Mnemonic: "MOV", Regs: "L,E", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "L <- E"},
+	0x6C: {Mnemonic: "MOV", Regs: "L,H", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "L <- H"},
+	0x6D: {Mnemonic: "MOV", Regs: "L,L", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "L <- L"},
+	0x6E: {Mnemonic: "MOV", Regs: "L,M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "L <- (HL)"},
+	0x6F: {Mnemonic: "MOV", Regs: "L,A", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "L <- A"},
+	0x70: {Mnemonic: "MOV", Regs: "M,B", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "(HL) <- B"},
+	0x71: {Mnemonic: "MOV", Regs: "M,C", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "(HL) <- C"},
+	0x72: {Mnemonic: "MOV", Regs: "M,D", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "(HL) <- D"},
+	0x73: {Mnemonic: "MOV", Regs: "M,E", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "(HL) <- E"},
+	0x74: {Mnemonic: "MOV", Regs: "M,H", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "(HL) <- H"},
+	0x75: {Mnemonic: "MOV", Regs: "M,L", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "(HL) <- L"},
+	0x76: {Mnemonic: "HLT", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Control, Description: "halt until an interrupt"},
+	0x77: {Mnemonic: "MOV", Regs: "M,A", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "(HL) <- A"},
+	0x78: {Mnemonic: "MOV", Regs: "A,B", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "A <- B"},
+	0x79: {Mnemonic: "MOV", Regs: "A,C", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "A <- C"},
+	0x7A: {Mnemonic: "MOV", Regs: "A,D", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "A <- D"},
+	0x7B: {Mnemonic: "MOV", Regs: "A,E", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "A <- E"},
+	0x7C: {Mnemonic: "MOV", Regs: "A,H", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "A <- H"},
+	0x7D: {Mnemonic: "MOV", Regs: "A,L", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "A <- L"},
+	0x7E: {Mnemonic: "MOV", Regs: "A,M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Class: Transfer, Description: "A <- (HL)"},
+	0x7F: {Mnemonic: "MOV", Regs: "A,A", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Transfer, Description: "A <- A"},
+	0x80: {Mnemonic: "ADD", Regs: "B", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + B"},
+	0x81: {Mnemonic: "ADD", Regs: "C", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + C"},
+	0x82: {Mnemonic: "ADD", Regs: "D", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + D"},
+	0x83: {Mnemonic: "ADD", Regs: "E", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + E"},
+	0x84: {Mnemonic: "ADD", Regs: "H", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + H"},
+	0x85: {Mnemonic: "ADD", Regs: "L", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + L"},
+	0x86: {Mnemonic: "ADD", Regs: "M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + (HL)"},
+	0x87: {Mnemonic: "ADD", Regs: "A", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + A"},
+	0x88: {Mnemonic: "ADC", Regs: "B", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + B + CY"},
+	0x89: {Mnemonic: "ADC", Regs: "C", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + C + CY"},
+	0x8A: {Mnemonic: "ADC", Regs: "D", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + D + CY"},
+	0x8B: {Mnemonic: "ADC", Regs: "E", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + E + CY"},
+	0x8C: {Mnemonic: "ADC", Regs: "H", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + H + CY"},
+	0x8D: {Mnemonic: "ADC", Regs: "L", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + L + CY"},
+	0x8E: {Mnemonic: "ADC", Regs: "M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + (HL) + CY"},
+	0x8F: {Mnemonic: "ADC", Regs: "A", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + A + CY"},
+	0x90: {Mnemonic: "SUB", Regs: "B", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - B"},
+	0x91: {Mnemonic: "SUB", Regs: "C", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - C"},
+	0x92: {Mnemonic: "SUB", Regs: "D", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - D"},
+	0x93: {Mnemonic: "SUB", Regs: "E", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - E"},
+	0x94: {Mnemonic: "SUB", Regs: "H", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - H"},
+	0x95: {Mnemonic: "SUB", Regs: "L", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - L"},
+	0x96: {Mnemonic: "SUB", Regs: "M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - (HL)"},
+	0x97: {Mnemonic: "SUB", Regs: "A", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - A"},
+	0x98: {Mnemonic: "SBB", Regs: "B", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - B - CY"},
+	0x99: {Mnemonic: "SBB", Regs: "C", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - C - CY"},
+	0x9A: {Mnemonic: "SBB", Regs: "D", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - D - CY"},
+	0x9B: {Mnemonic: "SBB", Regs: "E", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - E - CY"},
+	0x9C: {Mnemonic: "SBB", Regs: "H", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - H - CY"},
+	0x9D: {Mnemonic: "SBB", Regs: "L", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - L - CY"},
+	0x9E: {Mnemonic: "SBB", Regs: "M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - (HL) - CY"},
+	0x9F: {Mnemonic: "SBB", Regs: "A", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - A - CY"},
+	0xA0: {Mnemonic: "ANA", Regs: "B", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A & B"},
+	0xA1: {Mnemonic: "ANA", Regs: "C", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A & C"},
+	0xA2: {Mnemonic: "ANA", Regs: "D", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A & D"},
+	0xA3: {Mnemonic: "ANA", Regs: "E", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A & E"},
+	0xA4: {Mnemonic: "ANA", Regs: "H", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A & H"},
+	0xA5: {Mnemonic: "ANA", Regs: "L", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A & L"},
+	0xA6: {Mnemonic: "ANA", Regs: "M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A & (HL)"},
+	0xA7: {Mnemonic: "ANA", Regs: "A", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A & A"},
+	0xA8: {Mnemonic: "XRA", Regs: "B", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A ^ B"},
+	0xA9: {Mnemonic: "XRA", Regs: "C", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A ^ C"},
+	0xAA: {Mnemonic: "XRA", Regs: "D", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A ^ D"},
+	0xAB: {Mnemonic: "XRA", Regs: "E", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A ^ E"},
+	0xAC: {Mnemonic: "XRA", Regs: "H", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A ^ H"},
+	0xAD: {Mnemonic: "XRA", Regs: "L", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A ^ L"},
+	0xAE: {Mnemonic: "XRA", Regs: "M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A ^ (HL)"},
+	0xAF: {Mnemonic: "XRA", Regs: "A", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A ^ A"},
+	0xB0: {Mnemonic: "ORA", Regs: "B", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A | B"},
+	0xB1: {Mnemonic: "ORA", Regs: "C", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A | C"},
+	0xB2: {Mnemonic: "ORA", Regs: "D", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A | D"},
+	0xB3: {Mnemonic: "ORA", Regs: "E", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A | E"},
+	0xB4: {Mnemonic: "ORA", Regs: "H", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A | H"},
+	0xB5: {Mnemonic: "ORA", Regs: "L", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A | L"},
+	0xB6: {Mnemonic: "ORA", Regs: "M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A | (HL)"},
+	0xB7: {Mnemonic: "ORA", Regs: "A", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A | A"},
+	0xB8: {Mnemonic: "CMP", Regs: "B", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A - B"},
+	0xB9: {Mnemonic: "CMP", Regs: "C", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A - C"},
+	0xBA: {Mnemonic: "CMP", Regs: "D", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A - D"},
+	0xBB: {Mnemonic: "CMP", Regs: "E", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A - E"},
+	0xBC: {Mnemonic: "CMP", Regs: "H", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A - H"},
+	0xBD: {Mnemonic: "CMP", Regs: "L", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A - L"},
+	0xBE: {Mnemonic: "CMP", Regs: "M", Size: 1, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A - (HL)"},
+	0xBF: {Mnemonic: "CMP", Regs: "A", Size: 1, Cycles: 4, CyclesNotTaken: 4, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A - A"},
+	0xC0: {Mnemonic: "RNZ", Size: 1, Cycles: 11, CyclesNotTaken: 5, Class: Branch, Description: "if not Z, RET"},
+	0xC1: {Mnemonic: "POP", Regs: "B", Size: 1, Cycles: 10, CyclesNotTaken: 10, Class: Control, Description: "C <- (SP); B <- (SP+1); SP <- SP + 2"},
+	0xC2: {Mnemonic: "JNZ", Operand: Address, Size: 3, Cycles: 10, CyclesNotTaken: 10, Class: Branch, Description: "if not Z, PC <- adr"},
+	0xC3: {Mnemonic: "JMP", Operand: Address, Size: 3, Cycles: 10, CyclesNotTaken: 10, Class: Branch, Description: "PC <- adr"},
+	0xC4: {Mnemonic: "CNZ", Operand: Address, Size: 3, Cycles: 17, CyclesNotTaken: 11, Class: Branch, Description: "if not Z, CALL adr"},
+	0xC5: {Mnemonic: "PUSH", Regs: "B", Size: 1, Cycles: 11, CyclesNotTaken: 11, Class: Control, Description: "(SP-2) <- C; (SP-1) <- B; SP <- SP - 2"},
+	0xC6: {Mnemonic: "ADI", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + byte 2"},
+	0xC7: {Mnemonic: "RST", Operand: Vector, Vector: 0, Size: 1, Cycles: 11, CyclesNotTaken: 11, Class: Branch, Description: "CALL 00H"},
+	0xC8: {Mnemonic: "RZ", Size: 1, Cycles: 11, CyclesNotTaken: 5, Class: Branch, Description: "if Z, RET"},
+	0xC9: {Mnemonic: "RET", Size: 1, Cycles: 10, CyclesNotTaken: 10, Class: Branch, Description: "PC.lo <- (SP); PC.hi <- (SP+1); SP <- SP + 2"},
+	0xCA: {Mnemonic: "JZ", Operand: Address, Size: 3, Cycles: 10, CyclesNotTaken: 10, Class: Branch, Description: "if Z, PC <- adr"},
+	0xCC: {Mnemonic: "CZ", Operand: Address, Size: 3, Cycles: 17, CyclesNotTaken: 11, Class: Branch, Description: "if Z, CALL adr"},
+	0xCD: {Mnemonic: "CALL", Operand: Address, Size: 3, Cycles: 17, CyclesNotTaken: 17, Class: Branch, Description: "(SP-1) <- PC.hi; (SP-2) <- PC.lo; SP <- SP - 2; PC <- adr"},
+	0xCE: {Mnemonic: "ACI", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A + byte 2 + CY"},
+	0xCF: {Mnemonic: "RST", Operand: Vector, Vector: 1, Size: 1, Cycles: 11, CyclesNotTaken: 11, Class: Branch, Description: "CALL 08H"},
+	0xD0: {Mnemonic: "RNC", Size: 1, Cycles: 11, CyclesNotTaken: 5, Class: Branch, Description: "if not CY, RET"},
+	0xD1: {Mnemonic: "POP", Regs: "D", Size: 1, Cycles: 10, CyclesNotTaken: 10, Class: Control, Description: "E <- (SP); D <- (SP+1); SP <- SP + 2"},
+	0xD2: {Mnemonic: "JNC", Operand: Address, Size: 3, Cycles: 10, CyclesNotTaken: 10, Class: Branch, Description: "if not CY, PC <- adr"},
+	0xD3: {Mnemonic: "OUT", Operand: Port, Size: 2, Cycles: 10, CyclesNotTaken: 10, Class: Control, Description: "port byte 2 <- A"},
+	0xD4: {Mnemonic: "CNC", Operand: Address, Size: 3, Cycles: 17, CyclesNotTaken: 11, Class: Branch, Description: "if not CY, CALL adr"},
+	0xD5: {Mnemonic: "PUSH", Regs: "D", Size: 1, Cycles: 11, CyclesNotTaken: 11, Class: Control, Description: "(SP-2) <- E; (SP-1) <- D; SP <- SP - 2"},
+	0xD6: {Mnemonic: "SUI", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - byte 2"},
+	0xD7: {Mnemonic: "RST", Operand: Vector, Vector: 2, Size: 1, Cycles: 11, CyclesNotTaken: 11, Class: Branch, Description: "CALL 10H"},
+	0xD8: {Mnemonic: "RC", Size: 1, Cycles: 11, CyclesNotTaken: 5, Class: Branch, Description: "if CY, RET"},
+	0xDA: {Mnemonic: "JC", Operand: Address, Size: 3, Cycles: 10, CyclesNotTaken: 10, Class: Branch, Description: "if CY, PC <- adr"},
+	0xDB: {Mnemonic: "IN", Operand: Port, Size: 2, Cycles: 10, CyclesNotTaken: 10, Class: Control, Description: "A <- port byte 2"},
+	0xDC: {Mnemonic: "CC", Operand: Address, Size: 3, Cycles: 17, CyclesNotTaken: 11, Class: Branch, Description: "if CY, CALL adr"},
+	0xDE: {Mnemonic: "SBI", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Arithmetic, Description: "A <- A - byte 2 - CY"},
+	0xDF: {Mnemonic: "RST", Operand: Vector, Vector: 3, Size: 1, Cycles: 11, CyclesNotTaken: 11, Class: Branch, Description: "CALL 18H"},
+	0xE0: {Mnemonic: "RPO", Size: 1, Cycles: 11, CyclesNotTaken: 5, Class: Branch, Description: "if not P, RET"},
+	0xE1: {Mnemonic: "POP", Regs: "H", Size: 1, Cycles: 10, CyclesNotTaken: 10, Class: Control, Description: "L <- (SP); H <- (SP+1); SP <- SP + 2"},
+	0xE2: {Mnemonic: "JPO", Operand: Address, Size: 3, Cycles: 10, CyclesNotTaken: 10, Class: Branch, Description: "if not P, PC <- adr"},
+	0xE3: {Mnemonic: "XTHL", Size: 1, Cycles: 18, CyclesNotTaken: 18, Class: Control, Description: "L <-> (SP); H <-> (SP+1)"},
+	0xE4: {Mnemonic: "CPO", Operand: Address, Size: 3, Cycles: 17, CyclesNotTaken: 11, Class: Branch, Description: "if not P, CALL adr"},
+	0xE5: {Mnemonic: "PUSH", Regs: "H", Size: 1, Cycles: 11, CyclesNotTaken: 11, Class: Control, Description: "(SP-2) <- L; (SP-1) <- H; SP <- SP - 2"},
+	0xE6: {Mnemonic: "ANI", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A & byte 2"},
+	0xE7: {Mnemonic: "RST", Operand: Vector, Vector: 4, Size: 1, Cycles: 11, CyclesNotTaken: 11, Class: Branch, Description: "CALL 20H"},
+	0xE8: {Mnemonic: "RPE", Size: 1, Cycles: 11, CyclesNotTaken: 5, Class: Branch, Description: "if P, RET"},
+	0xE9: {Mnemonic: "PCHL", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Branch, Description: "PC.hi <- H; PC.lo <- L"},
+	0xEA: {Mnemonic: "JPE", Operand: Address, Size: 3, Cycles: 10, CyclesNotTaken: 10, Class: Branch, Description: "if P, PC <- adr"},
+	0xEB: {Mnemonic: "XCHG", Size: 1, Cycles: 4, CyclesNotTaken: 4, Class: Transfer, Description: "H <-> D; L <-> E"},
+	0xEC: {Mnemonic: "CPE", Operand: Address, Size: 3, Cycles: 17, CyclesNotTaken: 11, Class: Branch, Description: "if P, CALL adr"},
+	0xEE: {Mnemonic: "XRI", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A ^ byte 2"},
+	0xEF: {Mnemonic: "RST", Operand: Vector, Vector: 5, Size: 1, Cycles: 11, CyclesNotTaken: 11, Class: Branch, Description: "CALL 28H"},
+	0xF0: {Mnemonic: "RP", Size: 1, Cycles: 11, CyclesNotTaken: 5, Class: Branch, Description: "if not S, RET"},
+	0xF1: {Mnemonic: "POP", Regs: "PSW", Size: 1, Cycles: 10, CyclesNotTaken: 10, Flags: Z | S | P | CY | AC, Class: Control, Description: "flags <- (SP); A <- (SP+1); SP <- SP + 2"},
+	0xF2: {Mnemonic: "JP", Operand: Address, Size: 3, Cycles: 10, CyclesNotTaken: 10, Class: Branch, Description: "if not S, PC <- adr"},
+	0xF3: {Mnemonic: "DI", Size: 1, Cycles: 4, CyclesNotTaken: 4, Class: Control, Description: "disable interrupts"},
+	0xF4: {Mnemonic: "CP", Operand: Address, Size: 3, Cycles: 17, CyclesNotTaken: 11, Class: Branch, Description: "if not S, CALL adr"},
+	0xF5: {Mnemonic: "PUSH", Regs: "PSW", Size: 1, Cycles: 11, CyclesNotTaken: 11, Class: Control, Description: "(SP-2) <- flags; (SP-1) <- A; SP <- SP - 2"},
+	0xF6: {Mnemonic: "ORI", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A <- A | byte 2"},
+	0xF7: {Mnemonic: "RST", Operand: Vector, Vector: 6, Size: 1, Cycles: 11, CyclesNotTaken: 11, Class: Branch, Description: "CALL 30H"},
+	0xF8: {Mnemonic: "RM", Size: 1, Cycles: 11, CyclesNotTaken: 5, Class: Branch, Description: "if S, RET"},
+	0xF9: {Mnemonic: "SPHL", Size: 1, Cycles: 5, CyclesNotTaken: 5, Class: Control, Description: "SP <- HL"},
+	0xFA: {Mnemonic: "JM", Operand: Address, Size: 3, Cycles: 10, CyclesNotTaken: 10, Class: Branch, Description: "if S, PC <- adr"},
+	0xFB: {Mnemonic: "EI", Size: 1, Cycles: 4, CyclesNotTaken: 4, Class: Control, Description: "enable interrupts"},
+	0xFC: {Mnemonic: "CM", Operand: Address, Size: 3, Cycles: 17, CyclesNotTaken: 11, Class: Branch, Description: "if S, CALL adr"},
+	0xFE: {Mnemonic: "CPI", Operand: Immediate8, Size: 2, Cycles: 7, CyclesNotTaken: 7, Flags: Z | S | P | CY | AC, Class: Logical, Description: "A - byte 2"},
+	0xFF: {Mnemonic: "RST", Operand: Vector, Vector: 7, Size: 1, Cycles: 11, CyclesNotTaken: 11, Class: Branch, Description: "CALL 38H"},
+}
@@ -0,0 +1,223 @@
+// link links the relocatable modules assembled by package asm into a program.
+//
+// The code segments of the modules are placed one after the other, in the order the modules are given, and so are
+// their data segments, after the code unless told otherwise. The bytes assembled at absolute addresses stay there.
+// The words relative to the segments are relocated to where they're placed, and those relative to external
+// symbols get the addresses of the public symbols with the same name.
+package link
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/miguelff/8080/obj"
+	"github.com/miguelff/8080/symbols"
+)
+
+// Layout tells where the segments of the modules are placed
+type Layout struct {
+	// Code is the address of the first code segment
+	Code uint16
+	// Data is the address of the first data segment. When 0, the data segments follow the code ones.
+	Data uint16
+}
+
+// Placement is where a segment of a module was placed
+type Placement struct {
+	Module string
+	Kind   obj.Kind
+	Addr   uint16
+	Size   int
+}
+
+// Program is the result of linking modules
+type Program struct {
+	// Segments are the runs of contiguous bytes of the program, in ascending order of address
+	Segments []obj.Segment
+	// Symbols are the public symbols of the modules
+	Symbols *symbols.Table
+	// Placements are the segments of the modules, in ascending order of address
+	Placements []Placement
+
+	// modules are the modules defining each public symbol
+	modules map[string]string
+}
+
+// module is a module being linked, with the addresses of its segments
+type module struct {
+	*obj.Object
+	bases [obj.Data + 1]int
+}
+
+// Link links the given modules, placing their segments as told
+func Link(objects []*obj.Object, layout Layout) (*Program, error) {
+	modules := make([]*module, len(objects))
+	addr := int(layout.Code)
+	for i, o := range objects {
+		modules[i] = &module{Object: o}
+		modules[i].bases[obj.Code] = addr
+		addr += int(o.Code.Size)
+	}
+	if layout.Data != 0 {
+		addr = int(layout.Data)
+	}
+	for _, m := range modules {
+		m.bases[obj.Data] = addr
+		addr += int(m.Data.Size)
+	}
+
+	p := &Program{Symbols: symbols.New(), modules: make(map[string]string)}
+	publics := make(map[string]int)
+	for _, m := range modules {
+		for _, s := range m.Publics {
+			if other, ok := p.modules[s.Name]; ok {
+				return nil, fmt.Errorf("%s defined in %s and %s", s.Name, other, m.Name)
+			}
+			v := m.bases[s.Kind] + int(s.Value)
+			publics[s.Name] = v
+			p.modules[s.Name] = m.Name
+			p.Symbols.Add(s.Name, uint16(v))
+		}
+	}
+
+	var undefined []string
+	for _, m := range modules {
+		for _, name := range m.Externals {
+			if _, ok := publics[name]; !ok {
+				undefined = append(undefined, fmt.Sprintf("%s in %s", name, m.Name))
+			}
+		}
+	}
+	if len(undefined) > 0 {
+		return nil, fmt.Errorf("undefined external symbols: %s", strings.Join(undefined, ", "))
+	}
+
+	for _, m := range modules {
+		for _, sec := range []struct {
+			kind     obj.Kind
+			segments []obj.Segment
+			size     int
+		}{{obj.Abs, m.Abs, 0}, {obj.Code, m.Code.Bytes, int(m.Code.Size)}, {obj.Data, m.Data.Bytes, int(m.Data.Size)}} {
+			segments, err := m.place(sec.kind, sec.segments, publics)
+			if err != nil {
+				return nil, err
+			}
+			p.Segments = append(p.Segments, segments...)
+			if sec.kind == obj.Abs {
+				for _, s := range segments {
+					p.Placements = append(p.Placements, Placement{m.Name, obj.Abs, s.Addr, len(s.Data)})
+				}
+			} else if sec.size > 0 {
+				p.Placements = append(p.Placements, Placement{m.Name, sec.kind, uint16(m.bases[sec.kind]), sec.size})
+			}
+		}
+	}
+	if err := p.checkPlacements(); err != nil {
+		return nil, err
+	}
+	p.Segments = merge(p.Segments)
+	return p, nil
+}
+
+// checkPlacements sorts the placements by address, and checks they fit in memory without overlapping
+func (p *Program) checkPlacements() error {
+	sort.SliceStable(p.Placements, func(i, j int) bool { return p.Placements[i].Addr < p.Placements[j].Addr })
+	var last *Placement
+	for i := range p.Placements {
+		pl := &p.Placements[i]
+		if int(pl.Addr)+pl.Size > 0x10000 {
+			return fmt.Errorf("%s of %s at %04X of %d bytes beyond the 64K addressed by the 8080",
+				pl.Kind, pl.Module, pl.Addr, pl.Size)
+		}
+		if last != nil && int(last.Addr)+last.Size > int(pl.Addr) {
+			return fmt.Errorf("%s of %s at %04X overlaps %s of %s at %04X-%04X",
+				pl.Kind, pl.Module, pl.Addr, last.Kind, last.Module, last.Addr, int(last.Addr)+last.Size-1)
+		}
+		// the placement reaching the furthest is the one the next ones may overlap
+		if last == nil || int(pl.Addr)+pl.Size > int(last.Addr)+last.Size {
+			last = pl
+		}
+	}
+	return nil
+}
+
+// place returns the bytes of a segment of the module at their addresses, with their words relocated
+func (m *module) place(kind obj.Kind, segments []obj.Segment, publics map[string]int) ([]obj.Segment, error) {
+	base := m.bases[kind]
+	placed := make([]obj.Segment, len(segments))
+	for i, s := range segments {
+		placed[i] = obj.Segment{Addr: uint16(base + int(s.Addr)), Data: append([]byte(nil), s.Data...)}
+	}
+
+	for _, r := range m.Relocs {
+		if r.Kind != kind {
+			continue
+		}
+		var word []byte
+		for i, s := range segments {
+			if r.Offset >= s.Addr && int(r.Offset)+2 <= int(s.Addr)+len(s.Data) {
+				word = placed[i].Data[r.Offset-s.Addr:]
+				break
+			}
+		}
+		if word == nil {
+			return nil, fmt.Errorf("%s: relocation of undefined bytes at %s %04X", m.Name, kind, r.Offset)
+		}
+		to := publics[r.Ext]
+		if r.To != obj.External {
+			to = m.bases[r.To]
+		}
+		v := (uint16(word[0]) | uint16(word[1])<<8) + uint16(to)
+		word[0], word[1] = byte(v), byte(v>>8)
+	}
+	return placed, nil
+}
+
+// merge sorts the segments by address, joining the contiguous ones
+func merge(segments []obj.Segment) []obj.Segment {
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].Addr < segments[j].Addr })
+	var merged []obj.Segment
+	for _, s := range segments {
+		if n := len(merged); n > 0 && int(merged[n-1].Addr)+len(merged[n-1].Data) == int(s.Addr) {
+			merged[n-1].Data = append(merged[n-1].Data, s.Data...)
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// Binary returns the bytes of the program from its lowest address to its highest one, with the gaps between segments
+// filled with zeros, along with the address of the first one
+func (p *Program) Binary() ([]byte, uint16) {
+	if len(p.Segments) == 0 {
+		return nil, 0
+	}
+	first, last := p.Segments[0], p.Segments[len(p.Segments)-1]
+	bin := make([]byte, int(last.Addr)+len(last.Data)-int(first.Addr))
+	for _, s := range p.Segments {
+		copy(bin[s.Addr-first.Addr:], s.Data)
+	}
+	return bin, first.Addr
+}
+
+// WriteMap writes the map of the program: where the segments of each module were placed, as comments, and the
+// addresses of the public symbols, as EQU definitions, so package symbols reads it as a symbol file:
+//
+//	; CODE   0100-0116  main.o80
+//	; DATA   011C-012B  main.o80
+//	PRINT   EQU 0117H   ; lib.o80
+func (p *Program) WriteMap(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, pl := range p.Placements {
+		fmt.Fprintf(bw, "; %-5s  %04X-%04X  %s\n", pl.Kind, pl.Addr, int(pl.Addr)+pl.Size-1, pl.Module)
+	}
+	for _, name := range p.Symbols.Names() {
+		addr, _ := p.Symbols.Addr(name)
+		fmt.Fprintf(bw, "%-15s EQU %04XH   ; %s\n", name, addr, p.modules[name])
+	}
+	return bw.Flush()
+}
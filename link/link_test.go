@@ -0,0 +1,139 @@
+package link
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/miguelff/8080/obj"
+)
+
+// main calls PRINT with the address of its message, and jumps to START from the reset vector
+const mainObj = `OBJ8080
+SIZE CODE 0008
+SIZE DATA 0002
+BYTES ABS 0000 C30000
+BYTES CODE 0000 210000CD0000 ; LXI H,BUF / CALL PRINT
+BYTES CODE 0007 C9
+PUBLIC START CODE 0000
+EXTRN PRINT
+RELOC ABS 0001 CODE
+RELOC CODE 0001 DATA
+RELOC CODE 0004 EXTRN PRINT
+`
+
+// lib defines PRINT, reading its own data
+const libObj = `OBJ8080
+SIZE CODE 0004
+SIZE DATA 0001
+BYTES CODE 0000 3A0000C9 ; LDA VAR / RET
+BYTES DATA 0000 2A
+PUBLIC PRINT CODE 0000
+PUBLIC COUNT ABS 0007
+RELOC CODE 0001 DATA
+`
+
+func objects(t *testing.T, files ...string) []*obj.Object {
+	t.Helper()
+	objects := make([]*obj.Object, len(files))
+	for i, file := range files {
+		o, err := obj.Read(strings.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		o.Name = []string{"main.o80", "lib.o80", "other.o80"}[i]
+		objects[i] = o
+	}
+	return objects
+}
+
+func TestLink(t *testing.T) {
+	for _, tC := range []struct {
+		desc    string
+		layout  Layout
+		want    string
+		wantMap string
+	}{
+		{
+			"data after code",
+			Layout{Code: 0x100},
+			"0000: C3 00 01\n0100: 21 0C 01 CD 08 01\n0107: C9 3A 0E 01 C9\n010E: 2A\n",
+			"; ABS    0000-0002  main.o80\n" +
+				"; CODE   0100-0107  main.o80\n" +
+				"; CODE   0108-010B  lib.o80\n" +
+				"; DATA   010C-010D  main.o80\n" +
+				"; DATA   010E-010E  lib.o80\n" +
+				"COUNT           EQU 0007H   ; lib.o80\n" +
+				"START           EQU 0100H   ; main.o80\n" +
+				"PRINT           EQU 0108H   ; lib.o80\n",
+		},
+		{
+			"data at an address",
+			Layout{Code: 0x100, Data: 0x2000},
+			"0000: C3 00 01\n0100: 21 00 20 CD 08 01\n0107: C9 3A 02 20 C9\n2002: 2A\n",
+			"",
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			p, err := Link(objects(t, mainObj, libObj), tC.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got strings.Builder
+			for _, s := range p.Segments {
+				fmt.Fprintf(&got, "%04X: % X\n", s.Addr, s.Data)
+			}
+			if got.String() != tC.want {
+				t.Errorf("got segments\n%s\nwant\n%s", got.String(), tC.want)
+			}
+			if tC.wantMap == "" {
+				return
+			}
+			var m bytes.Buffer
+			if err := p.WriteMap(&m); err != nil {
+				t.Fatal(err)
+			}
+			if m.String() != tC.wantMap {
+				t.Errorf("got map\n%s\nwant\n%s", m.String(), tC.wantMap)
+			}
+		})
+	}
+}
+
+func TestLink_Errors(t *testing.T) {
+	for _, tC := range []struct {
+		desc   string
+		files  []string
+		layout Layout
+		want   string
+	}{
+		{"undefined external", []string{mainObj}, Layout{Code: 0x100}, "undefined external symbols: PRINT in main.o80"},
+		{
+			"duplicate public",
+			[]string{mainObj, libObj, "OBJ8080\nPUBLIC PRINT ABS 0000\n"},
+			Layout{Code: 0x100},
+			"PRINT defined in lib.o80 and other.o80",
+		},
+		{
+			"overlap",
+			[]string{mainObj, libObj},
+			Layout{Code: 0},
+			"CODE of main.o80 at 0000 overlaps ABS of main.o80 at 0000-0002",
+		},
+		{
+			"beyond memory",
+			[]string{mainObj, libObj},
+			Layout{Code: 0xFFFC},
+			"CODE of main.o80 at FFFC of 8 bytes beyond the 64K addressed by the 8080",
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := Link(objects(t, tC.files...), tC.layout)
+			if err == nil || err.Error() != tC.want {
+				t.Errorf("got error %v, want %q", err, tC.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+// obj reads and writes the relocatable object files (.o80) produced by package asm and linked by package link.
+//
+// Object files are text, with a record per line, and comments starting with ;. The first line identifies the format,
+// and hexadecimal numbers are used throughout:
+//
+//	OBJ8080
+//	SIZE CODE 0012                     ; size of the code segment
+//	SIZE DATA 0100                     ; size of the data segment
+//	BYTES CODE 0000 3E01CD0000C9       ; bytes at an offset of a segment, or at an address for ABS
+//	BYTES ABS 0000 C30000
+//	PUBLIC START CODE 0000             ; symbol defined for other modules, with its segment and value
+//	EXTRN PRINT                        ; symbol used from other modules
+//	RELOC CODE 0004 DATA               ; word at an offset of a segment, relative to the start of a segment
+//	RELOC CODE 0007 EXTRN PRINT        ; word relative to an external symbol
+//
+// The code and data segments are placed by the linker, while the absolute one (ABS) holds the bytes assembled at
+// fixed addresses. The words relocated hold their offset from the start of the segment or external symbol they
+// are relative to, to which the linker adds its address.
+package obj
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Kind tells what a value is relative to
+type Kind int
+
+// Kinds of values
+const (
+	// Abs values are absolute numbers and addresses
+	Abs Kind = iota
+	// Code values are relative to the start of the code segment of the module
+	Code
+	// Data values are relative to the start of the data segment of the module
+	Data
+	// External values are relative to a symbol defined in another module
+	External
+)
+
+var kindNames = []string{"ABS", "CODE", "DATA", "EXTRN"}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// magic is the first line of object files
+const magic = "OBJ8080"
+
+// bytesPerRecord is the maximum number of bytes written per BYTES record
+const bytesPerRecord = 16
+
+// Segment is a run of contiguous bytes, at an address or an offset from the start of a segment
+type Segment struct {
+	Addr uint16
+	Data []byte
+}
+
+// Section is a segment of the module placed by the linker: its size, and the bytes defined in it
+type Section struct {
+	Size uint16
+	// Bytes are the runs of bytes defined, in ascending order of offset. Those left out, like the ones reserved with
+	// DS, are undefined.
+	Bytes []Segment
+}
+
+// Symbol is a symbol defined in a module for the others
+type Symbol struct {
+	Name  string
+	Kind  Kind
+	Value uint16
+}
+
+// Reloc is a 16-bit word that must be relocated when linking
+type Reloc struct {
+	// Kind and Offset locate the word, in a segment of the module or at an address for Abs
+	Kind   Kind
+	Offset uint16
+	// To is what the word is relative to, Code, Data or External, in which case Ext names the symbol
+	To  Kind
+	Ext string
+}
+
+// Object is a module of a program assembled separately
+type Object struct {
+	// Name identifies the module, like the path of its file
+	Name string
+	// Abs are the runs of bytes at fixed addresses, in ascending order of address
+	Abs        []Segment
+	Code, Data Section
+	Publics    []Symbol
+	Externals  []string
+	Relocs     []Reloc
+}
+
+// Write writes the object file
+func Write(w io.Writer, o *Object) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, magic)
+	fmt.Fprintf(bw, "SIZE CODE %04X\n", o.Code.Size)
+	fmt.Fprintf(bw, "SIZE DATA %04X\n", o.Data.Size)
+	writeBytes(bw, Abs, o.Abs)
+	writeBytes(bw, Code, o.Code.Bytes)
+	writeBytes(bw, Data, o.Data.Bytes)
+	for _, s := range o.Publics {
+		fmt.Fprintf(bw, "PUBLIC %s %s %04X\n", s.Name, s.Kind, s.Value)
+	}
+	for _, name := range o.Externals {
+		fmt.Fprintf(bw, "EXTRN %s\n", name)
+	}
+	for _, r := range o.Relocs {
+		to := r.To.String()
+		if r.To == External {
+			to += " " + r.Ext
+		}
+		fmt.Fprintf(bw, "RELOC %s %04X %s\n", r.Kind, r.Offset, to)
+	}
+	return bw.Flush()
+}
+
+func writeBytes(w io.Writer, k Kind, segments []Segment) {
+	for _, s := range segments {
+		for i := 0; i < len(s.Data); i += bytesPerRecord {
+			end := i + bytesPerRecord
+			if end > len(s.Data) {
+				end = len(s.Data)
+			}
+			fmt.Fprintf(w, "BYTES %s %04X %s\n", k, int(s.Addr)+i, strings.ToUpper(hex.EncodeToString(s.Data[i:end])))
+		}
+	}
+}
+
+// Read parses an object file
+func Read(r io.Reader) (*Object, error) {
+	o := &Object{}
+	s := bufio.NewScanner(r)
+	n := 0
+	for s.Scan() {
+		n++
+		line := s.Text()
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if n == 1 {
+			if len(fields) != 1 || fields[0] != magic {
+				return nil, fmt.Errorf("not an object file")
+			}
+			continue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if err := o.parseRecord(fields); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("not an object file")
+	}
+	return o, o.check()
+}
+
+func (o *Object) parseRecord(fields []string) error {
+	want := map[string]int{"SIZE": 3, "BYTES": 4, "PUBLIC": 4, "EXTRN": 2, "RELOC": 4}[fields[0]]
+	switch {
+	case want == 0:
+		return fmt.Errorf("unknown record %s", fields[0])
+	case len(fields) != want && !(fields[0] == "RELOC" && len(fields) == 5):
+		return fmt.Errorf("%s record with %d fields, want %d", fields[0], len(fields), want)
+	}
+
+	switch fields[0] {
+	case "SIZE":
+		size, err := parseWord(fields[2])
+		if err != nil {
+			return err
+		}
+		switch fields[1] {
+		case "CODE":
+			o.Code.Size = size
+		case "DATA":
+			o.Data.Size = size
+		default:
+			return fmt.Errorf("invalid segment %s", fields[1])
+		}
+	case "BYTES":
+		k, err := parseKind(fields[1], Abs, Code, Data)
+		if err != nil {
+			return err
+		}
+		addr, err := parseWord(fields[2])
+		if err != nil {
+			return err
+		}
+		data, err := hex.DecodeString(fields[3])
+		if err != nil {
+			return fmt.Errorf("invalid bytes %s", fields[3])
+		}
+		o.addBytes(k, Segment{Addr: addr, Data: data})
+	case "PUBLIC":
+		k, err := parseKind(fields[2], Abs, Code, Data)
+		if err != nil {
+			return err
+		}
+		v, err := parseWord(fields[3])
+		if err != nil {
+			return err
+		}
+		o.Publics = append(o.Publics, Symbol{Name: fields[1], Kind: k, Value: v})
+	case "EXTRN":
+		o.Externals = append(o.Externals, fields[1])
+	case "RELOC":
+		k, err := parseKind(fields[1], Abs, Code, Data)
+		if err != nil {
+			return err
+		}
+		offset, err := parseWord(fields[2])
+		if err != nil {
+			return err
+		}
+		to, err := parseKind(fields[3], Code, Data, External)
+		if err != nil {
+			return err
+		}
+		r := Reloc{Kind: k, Offset: offset, To: to}
+		if (to == External) != (len(fields) == 5) {
+			return fmt.Errorf("only RELOC records relative to EXTRN name a symbol")
+		}
+		if to == External {
+			r.Ext = fields[4]
+		}
+		o.Relocs = append(o.Relocs, r)
+	}
+	return nil
+}
+
+// addBytes adds the bytes to the given segment, joining them to the previous run when contiguous
+func (o *Object) addBytes(k Kind, s Segment) {
+	segments := &o.Abs
+	switch k {
+	case Code:
+		segments = &o.Code.Bytes
+	case Data:
+		segments = &o.Data.Bytes
+	}
+	if n := len(*segments); n > 0 {
+		last := &(*segments)[n-1]
+		if int(last.Addr)+len(last.Data) == int(s.Addr) {
+			last.Data = append(last.Data, s.Data...)
+			return
+		}
+	}
+	*segments = append(*segments, s)
+}
+
+// check validates the references between the records of the object
+func (o *Object) check() error {
+	for _, sec := range []struct {
+		kind Kind
+		Section
+	}{{Code, o.Code}, {Data, o.Data}} {
+		for _, s := range sec.Bytes {
+			if int(s.Addr)+len(s.Data) > int(sec.Size) {
+				return fmt.Errorf("bytes at %s %04X beyond the size of the segment", sec.kind, s.Addr)
+			}
+		}
+	}
+	externals := make(map[string]bool)
+	for _, name := range o.Externals {
+		externals[name] = true
+	}
+	for _, r := range o.Relocs {
+		if r.To == External && !externals[r.Ext] {
+			return fmt.Errorf("relocation relative to %s, not declared with EXTRN", r.Ext)
+		}
+	}
+	return nil
+}
+
+func parseKind(s string, valid ...Kind) (Kind, error) {
+	for _, k := range valid {
+		if s == k.String() {
+			return k, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid segment %s", s)
+}
+
+func parseWord(s string) (uint16, error) {
+	v, err := strconv.ParseUint(s, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %s", s)
+	}
+	return uint16(v), nil
+}
+
+// Load reads the object file at the given path, named after it
+func Load(path string) (*Object, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	o, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	o.Name = path
+	return o, nil
+}
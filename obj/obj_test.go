@@ -0,0 +1,75 @@
+package obj
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteRead(t *testing.T) {
+	o := &Object{
+		Abs: []Segment{{Addr: 0, Data: []byte{0xC3, 0x00, 0x00}}},
+		Code: Section{Size: 0x20, Bytes: []Segment{
+			{Addr: 0, Data: bytes.Repeat([]byte{0x00}, 20)},
+			{Addr: 0x18, Data: []byte{0xC9}},
+		}},
+		Data:      Section{Size: 0x10},
+		Publics:   []Symbol{{Name: "START", Kind: Code, Value: 0}, {Name: "COUNT", Kind: Abs, Value: 7}},
+		Externals: []string{"PRINT"},
+		Relocs:    []Reloc{{Kind: Abs, Offset: 1, To: Code}, {Kind: Code, Offset: 4, To: External, Ext: "PRINT"}},
+	}
+
+	var b bytes.Buffer
+	if err := Write(&b, o); err != nil {
+		t.Fatal(err)
+	}
+	want := "OBJ8080\n" +
+		"SIZE CODE 0020\n" +
+		"SIZE DATA 0010\n" +
+		"BYTES ABS 0000 C30000\n" +
+		"BYTES CODE 0000 00000000000000000000000000000000\n" +
+		"BYTES CODE 0010 00000000\n" +
+		"BYTES CODE 0018 C9\n" +
+		"PUBLIC START CODE 0000\n" +
+		"PUBLIC COUNT ABS 0007\n" +
+		"EXTRN PRINT\n" +
+		"RELOC ABS 0001 CODE\n" +
+		"RELOC CODE 0004 EXTRN PRINT\n"
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	got, err := Read(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, o) {
+		t.Errorf("read back %+v, want %+v", got, o)
+	}
+}
+
+func TestRead_Errors(t *testing.T) {
+	for _, tC := range []struct {
+		desc string
+		file string
+		want string
+	}{
+		{"not an object", "SIZE CODE 0010\n", "not an object file"},
+		{"empty", "", "not an object file"},
+		{"unknown record", "OBJ8080\nFOO 1\n", "line 2: unknown record FOO"},
+		{"missing fields", "OBJ8080\nSIZE CODE\n", "line 2: SIZE record with 2 fields, want 3"},
+		{"invalid segment", "OBJ8080\nBYTES EXTRN 0000 00\n", "line 2: invalid segment EXTRN"},
+		{"invalid number", "OBJ8080\nSIZE CODE 10000\n", "line 2: invalid number 10000"},
+		{"invalid bytes", "OBJ8080 ; relocatable\nBYTES ABS 0000 0G\n", "line 2: invalid bytes 0G"},
+		{"undeclared external", "OBJ8080\nRELOC ABS 0001 EXTRN X\n", "relocation relative to X, not declared with EXTRN"},
+		{"bytes beyond size", "OBJ8080\nSIZE CODE 0001\nBYTES CODE 0000 0000\n", "bytes at CODE 0000 beyond the size of the segment"},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tC.file))
+			if err == nil || err.Error() != tC.want {
+				t.Errorf("got error %v, want %q", err, tC.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+//go:build linux
+// +build linux
+
+package serial
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// OpenPTY creates a console backed by a new pseudo-terminal. Terminal programs like minicom or screen can then be
+// attached to the slave device reported by Name.
+//
+// The slave side is kept open and in raw mode for as long as the console lives, so terminal programs can come and go
+// without the emulated program noticing.
+func OpenPTY(cfg Config) (*Console, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var unlock int32
+	if err := ioctl(master.Fd(), syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		_ = master.Close()
+		return nil, fmt.Errorf("unlocking pseudo-terminal: %w", err)
+	}
+	var n uint32
+	if err := ioctl(master.Fd(), syscall.TIOCGPTN, unsafe.Pointer(&n)); err != nil {
+		_ = master.Close()
+		return nil, fmt.Errorf("getting pseudo-terminal number: %w", err)
+	}
+
+	name := fmt.Sprintf("/dev/pts/%d", n)
+	slave, err := os.OpenFile(name, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		_ = master.Close()
+		return nil, err
+	}
+	if err := makeRaw(slave.Fd()); err != nil {
+		_ = slave.Close()
+		_ = master.Close()
+		return nil, fmt.Errorf("setting %s in raw mode: %w", name, err)
+	}
+
+	c := newConsole(cfg, name, ptyPair{master: master, slave: slave})
+	c.peer = master
+	go c.receive(master)
+	return c, nil
+}
+
+// ptyPair closes both ends of a pseudo-terminal
+type ptyPair struct {
+	master *os.File
+	slave  *os.File
+}
+
+func (p ptyPair) Close() error {
+	err := p.master.Close()
+	if serr := p.slave.Close(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// makeRaw disables echo, line buffering and any character translation in the terminal
+func makeRaw(fd uintptr) error {
+	var t syscall.Termios
+	if err := ioctl(fd, syscall.TCGETS, unsafe.Pointer(&t)); err != nil {
+		return err
+	}
+
+	t.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR |
+		syscall.ICRNL | syscall.IXON
+	t.Oflag &^= syscall.OPOST
+	t.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	t.Cflag &^= syscall.CSIZE | syscall.PARENB
+	t.Cflag |= syscall.CS8
+	t.Cc[syscall.VMIN] = 1
+	t.Cc[syscall.VTIME] = 0
+
+	return ioctl(fd, syscall.TCSETS, unsafe.Pointer(&t))
+}
+
+func ioctl(fd, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
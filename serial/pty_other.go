@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package serial
+
+// OpenPTY creates a console backed by a new pseudo-terminal. Pseudo-terminals are only supported on Linux, everywhere
+// else ErrUnsupported is returned: use Listen instead.
+func OpenPTY(_ Config) (*Console, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,243 @@
+// serial bridges the serial console of an emulated machine to the host, either through a local TCP socket or a
+// pseudo-terminal, so tools like telnet or minicom can be attached to it.
+package serial
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/miguelff/8080/emu"
+)
+
+// rxBufferSize is the amount of received bytes buffered until the emulated program reads them. When the buffer is
+// full, the host side is not read until there's room again.
+const rxBufferSize = 4096
+
+// txBufferSize is the amount of transmitted bytes buffered until the host side takes them. While the buffer is full,
+// the status port reports the transmitter as not ready, and further bytes are discarded.
+const txBufferSize = 4096
+
+// ErrUnsupported is returned when the host cannot provide the requested kind of console
+var ErrUnsupported = errors.New("serial: unsupported on this platform")
+
+// Config describes how a serial board is wired to the I/O ports of the computer.
+//
+// Programs poll the status port until the receiver has a byte ready (or the transmitter is ready to accept one), and
+// then read (or write) it through the data port.
+type Config struct {
+	// StatusPort is the port the status byte is read from
+	StatusPort byte
+	// DataPort is the port bytes are received from and transmitted to
+	DataPort byte
+	// RxReady is the status bit mask signaling a received byte is waiting in the data port
+	RxReady byte
+	// TxReady is the status bit mask signaling the transmitter accepts a new byte
+	TxReady byte
+	// ActiveLow inverts the status bits, for boards signaling readiness with a 0
+	ActiveLow bool
+}
+
+var (
+	// SIO is the layout of the MITS 88-SIO board used by the Altair 8800: status on port 0, data on port 1, and
+	// active-low status bits.
+	SIO = Config{StatusPort: 0x00, DataPort: 0x01, RxReady: 0x01, TxReady: 0x80, ActiveLow: true}
+
+	// SIO2 is the layout of the first channel of the MITS 88-2SIO board, built around a Motorola 6850 ACIA, which most
+	// CP/M BIOSes for the Altair expect.
+	SIO2 = Config{StatusPort: 0x10, DataPort: 0x11, RxReady: 0x01, TxReady: 0x02}
+)
+
+// Console is a serial device whose other end lives on the host. It implements emu.PortHandler for the status and
+// data ports of its Config.
+//
+// Bytes coming from the host are buffered in the background, so reading the status or data ports never blocks the
+// emulated program: when nothing has been received the status port reports the receiver as not ready, and the data
+// port reads 0. Bytes transmitted are buffered as well, and written to the host in the background, so a peer not
+// reading them never stalls the emulated program: when the buffer fills up the status port reports the transmitter as
+// not ready, and the bytes written anyway are discarded. Bytes transmitted while no peer is connected are discarded.
+type Console struct {
+	Config
+
+	rx     chan byte
+	tx     chan byte
+	closer io.Closer
+	name   string
+	// accepts tells whether peers come and go (TCP) or the console has a single fixed one (pseudo-terminal)
+	accepts bool
+
+	mu   sync.Mutex
+	peer io.ReadWriteCloser
+	done chan struct{}
+}
+
+func newConsole(cfg Config, name string, closer io.Closer) *Console {
+	c := &Console{
+		Config: cfg,
+		rx:     make(chan byte, rxBufferSize),
+		tx:     make(chan byte, txBufferSize),
+		closer: closer,
+		name:   name,
+		done:   make(chan struct{}),
+	}
+	go c.transmit()
+	return c
+}
+
+// Listen creates a console that accepts connections on the given local TCP address, for instance "localhost:8080".
+// Only one peer is served at a time: a new connection replaces the previous one.
+func Listen(addr string, cfg Config) (*Console, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newConsole(cfg, l.Addr().String(), l)
+	c.accepts = true
+	go c.accept(l)
+	return c, nil
+}
+
+// Name returns where the host side of the console can be reached: the listening address of a TCP console, or the
+// path of the slave device of a pseudo-terminal.
+func (c *Console) Name() string {
+	return c.name
+}
+
+// Attach wires the console to the status and data ports of the given computer
+func (c *Console) Attach(computer *emu.Computer) {
+	computer.Attach(c, c.StatusPort, c.DataPort)
+}
+
+// In implements emu.PortHandler
+func (c *Console) In(port byte) (byte, error) {
+	switch port {
+	case c.StatusPort:
+		return c.status(), nil
+	case c.DataPort:
+		select {
+		case b := <-c.rx:
+			return b, nil
+		default:
+			return 0, nil
+		}
+	}
+	return 0, nil
+}
+
+// Out implements emu.PortHandler
+func (c *Console) Out(port byte, v byte) error {
+	if port != c.DataPort {
+		return nil
+	}
+	select {
+	case c.tx <- v:
+	default:
+	}
+	return nil
+}
+
+// Close stops accepting peers and releases the host side of the console
+func (c *Console) Close() error {
+	c.mu.Lock()
+	select {
+	case <-c.done:
+		c.mu.Unlock()
+		return nil
+	default:
+		close(c.done)
+	}
+	peer := c.peer
+	c.peer = nil
+	c.mu.Unlock()
+
+	if c.accepts && peer != nil {
+		_ = peer.Close()
+	}
+	return c.closer.Close()
+}
+
+// status computes the byte read from the status port
+func (c *Console) status() byte {
+	var s byte
+	if len(c.tx) < cap(c.tx) {
+		s |= c.TxReady
+	}
+	if len(c.rx) > 0 {
+		s |= c.RxReady
+	}
+	if c.ActiveLow {
+		s ^= c.RxReady | c.TxReady
+	}
+	return s
+}
+
+func (c *Console) accept(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		c.mu.Lock()
+		prev := c.peer
+		c.peer = conn
+		c.mu.Unlock()
+
+		if prev != nil {
+			_ = prev.Close()
+		}
+		go c.receive(conn)
+	}
+}
+
+// receive buffers the bytes read from the given peer until it disconnects or the console is closed
+func (c *Console) receive(peer io.ReadWriteCloser) {
+	defer c.drop(peer)
+
+	buf := make([]byte, 256)
+	for {
+		n, err := peer.Read(buf)
+		for _, b := range buf[:n] {
+			select {
+			case c.rx <- b:
+			case <-c.done:
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// transmit writes the bytes transmitted to the current peer, if any, until the console is closed
+func (c *Console) transmit() {
+	for {
+		select {
+		case b := <-c.tx:
+			c.mu.Lock()
+			peer := c.peer
+			c.mu.Unlock()
+
+			if peer == nil {
+				continue
+			}
+			if _, err := peer.Write([]byte{b}); err != nil {
+				c.drop(peer)
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// drop forgets the given peer if it is still the current one
+func (c *Console) drop(peer io.ReadWriteCloser) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.peer == peer {
+		c.peer = nil
+	}
+}
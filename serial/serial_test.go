@@ -0,0 +1,185 @@
+package serial
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/miguelff/8080/emu"
+)
+
+// waitRx polls the status port until the receiver is ready, like an emulated program would
+func waitRx(t *testing.T, c *Console) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s, _ := c.In(c.StatusPort)
+		if (s&c.RxReady != 0) != c.ActiveLow {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for the receiver to be ready")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConsole_Status(t *testing.T) {
+	for _, tC := range []struct {
+		desc string
+		cfg  Config
+		rx   bool
+		want byte
+	}{
+		{"SIO2 idle", SIO2, false, 0x02},
+		{"SIO2 byte waiting", SIO2, true, 0x03},
+		{"SIO idle", SIO, false, 0x01},
+		{"SIO byte waiting", SIO, true, 0x00},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			c := newConsole(tC.cfg, "test", io.NopCloser(nil))
+			if tC.rx {
+				c.rx <- 'A'
+			}
+			if got, _ := c.In(c.StatusPort); got != tC.want {
+				t.Errorf("got %02X, want %02X", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestListen(t *testing.T) {
+	c, err := Listen("127.0.0.1:0", SIO2)
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	defer c.Close()
+
+	computer := emu.Load(nil)
+	c.Attach(computer)
+
+	conn, err := net.Dial("tcp", c.Name())
+	if err != nil {
+		t.Fatalf("unexpected error connecting: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	var got []byte
+	for i := 0; i < 2; i++ {
+		waitRx(t, c)
+		b, _ := c.In(c.DataPort)
+		got = append(got, b)
+	}
+	if string(got) != "hi" {
+		t.Errorf("received %q, want %q", got, "hi")
+	}
+	if b, _ := c.In(c.DataPort); b != 0 {
+		t.Errorf("reading an empty receiver: got %02X, want 00", b)
+	}
+
+	// OUT $11 with A='!'
+	copy(computer.Mem, []byte{0xD3, 0x11})
+	computer.A = '!'
+	if err := computer.Step(emu.DebugNone); err != nil {
+		t.Fatalf("unexpected error executing OUT: %v", err)
+	}
+	buf := make([]byte, 1)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("unexpected error reading from the console: %v", err)
+	}
+	if !bytes.Equal(buf, []byte("!")) {
+		t.Errorf("transmitted %q, want %q", buf, "!")
+	}
+}
+
+func TestOpenPTY(t *testing.T) {
+	c, err := OpenPTY(SIO)
+	if err == ErrUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Skipf("cannot create a pseudo-terminal: %v", err)
+	}
+	defer c.Close()
+
+	tty, err := os.OpenFile(c.Name(), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("unexpected error opening %s: %v", c.Name(), err)
+	}
+	defer tty.Close()
+
+	if _, err := tty.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	waitRx(t, c)
+	if b, _ := c.In(c.DataPort); b != 'x' {
+		t.Errorf("received %q, want %q", b, 'x')
+	}
+
+	if err := c.Out(c.DataPort, 'y'); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(tty, buf); err != nil {
+		t.Fatalf("unexpected error reading from %s: %v", c.Name(), err)
+	}
+	if buf[0] != 'y' {
+		t.Errorf("transmitted %q, want %q", buf[0], 'y')
+	}
+}
+
+// stalledPeer is a peer that doesn't take the bytes written to it until released
+type stalledPeer struct {
+	io.Reader
+	written chan struct{}
+	release chan struct{}
+}
+
+func (p *stalledPeer) Write(b []byte) (int, error) {
+	select {
+	case <-p.written:
+	default:
+		close(p.written)
+	}
+	<-p.release
+	return 0, io.ErrClosedPipe
+}
+
+func (p *stalledPeer) Close() error { return nil }
+
+func TestConsole_OutNotRead(t *testing.T) {
+	c := newConsole(SIO2, "test", io.NopCloser(nil))
+	peer := &stalledPeer{Reader: bytes.NewReader(nil), written: make(chan struct{}), release: make(chan struct{})}
+	c.peer = peer
+	t.Cleanup(func() {
+		close(peer.release)
+		c.Close()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2*txBufferSize; i++ {
+			_ = c.Out(c.DataPort, 'x')
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("writing to a peer not reading blocks")
+	}
+
+	// fill the room left by the byte being written
+	<-peer.written
+	_ = c.Out(c.DataPort, 'x')
+	if got, _ := c.In(c.StatusPort); got != 0x00 {
+		t.Errorf("got status %02X, want %02X", got, 0x00)
+	}
+}
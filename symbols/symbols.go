@@ -0,0 +1,286 @@
+// symbols reads the symbol files produced by 8080 assemblers and linkers, mapping addresses to names.
+//
+// These formats are understood, and can be mixed in the same file:
+//
+//	START   EQU 0100H        ; EQU definitions, also with a colon after the name
+//	_main = $0103 ; ...      ; z88dk map files, and "DEFC name = value" lines
+//	0106 LOOP    0109 DONE   ; CP/M .SYM files, holding several "address name" pairs per line
+//
+// Numbers can be written in hexadecimal with a 0x or $ prefix, or an H suffix, and in decimal otherwise, except in
+// CP/M .SYM files where they are always hexadecimal.
+//
+// The listings written by package asm (.PRN files) are read with ReadListing, which also maps the addresses of the
+// instructions to the lines of source code assembling them.
+package symbols
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Table maps addresses to symbol names, and the other way around
+type Table struct {
+	names map[uint16]string
+	addrs map[string]uint16
+	// lines are the lines of source code, by address
+	lines map[uint16]string
+}
+
+// New creates an empty table
+func New() *Table {
+	return &Table{
+		names: make(map[uint16]string),
+		addrs: make(map[string]uint16),
+		lines: make(map[uint16]string),
+	}
+}
+
+// Add defines a symbol. When several symbols share an address, the first one added names it.
+func (t *Table) Add(name string, addr uint16) {
+	if _, ok := t.names[addr]; !ok {
+		t.names[addr] = name
+	}
+	t.addrs[name] = addr
+}
+
+// Name returns the name of the symbol at the given address. A nil table has no symbols.
+func (t *Table) Name(addr uint16) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+	name, ok := t.names[addr]
+	return name, ok
+}
+
+// AddLine sets the line of source code assembled at the given address
+func (t *Table) AddLine(addr uint16, line string) {
+	t.lines[addr] = line
+}
+
+// Line returns the line of source code assembled at the given address, when read from a listing
+func (t *Table) Line(addr uint16) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+	line, ok := t.lines[addr]
+	return line, ok
+}
+
+// Addr returns the address of the symbol with the given name
+func (t *Table) Addr(name string) (uint16, bool) {
+	if t == nil {
+		return 0, false
+	}
+	addr, ok := t.addrs[name]
+	return addr, ok
+}
+
+// Len returns the number of symbols in the table
+func (t *Table) Len() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.addrs)
+}
+
+// Names returns the names of the symbols sorted by address, and then by name
+func (t *Table) Names() []string {
+	if t == nil {
+		return nil
+	}
+	names := make([]string, 0, len(t.addrs))
+	for name := range t.addrs {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if t.addrs[names[i]] != t.addrs[names[j]] {
+			return t.addrs[names[i]] < t.addrs[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// Read parses a symbol file
+func Read(r io.Reader) (*Table, error) {
+	t := New()
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		// CP/M files are padded with ^Z up to the end of the last record
+		if i := strings.IndexByte(line, 0x1A); i >= 0 {
+			line = line[:i]
+		}
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if err := t.parseLine(fields); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+	}
+	return t, s.Err()
+}
+
+// These are the columns of the lines of listings: the address, followed by a mark when it's relocatable, the bytes
+// assembled, the line number, followed by a + for the lines of included files and expansions, and the source code
+//
+//	0100  3E 41          12  LOOP:   MVI A,'A'
+const (
+	listingMark   = 4
+	listingBytes  = 6
+	listingNumber = 18
+	listingSource = 25
+)
+
+// ReadListing parses a listing written by package asm: its lines, and the symbol table following them after an
+// empty line, in any of the formats understood by Read. The lines assembling bytes are mapped to their addresses,
+// with their line numbers, as in "12  LOOP:   MVI A,'A'". Only absolute addresses are read: the lines and symbols of
+// relocatable code, marked with ' and " after their offsets, or * after values relative to external symbols, are
+// skipped, as their addresses are only known once linked.
+func ReadListing(r io.Reader) (*Table, error) {
+	t := New()
+	s := bufio.NewScanner(r)
+	symbols := false
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if strings.TrimSpace(line) == "" {
+			symbols = true
+			continue
+		}
+		if symbols {
+			fields := absolute(strings.Fields(line))
+			if len(fields) == 0 {
+				continue
+			}
+			if err := t.parseLine(fields); err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			continue
+		}
+
+		// lines not assembling bytes, and those continuing the bytes of the previous one, have no address or number
+		if len(line) < listingSource || line[listingBytes] == ' ' || line[listingBytes] == '=' {
+			continue
+		}
+		if strings.ContainsRune(relMarks, rune(line[listingMark])) {
+			continue
+		}
+		number := strings.TrimSpace(line[listingNumber : listingSource-2])
+		if number == "" {
+			continue
+		}
+		addr, err := strconv.ParseUint(line[:4], 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid address %q", n, line[:4])
+		}
+		t.AddLine(uint16(addr), number+"  "+line[listingSource:])
+	}
+	return t, s.Err()
+}
+
+// relMarks are the marks following the relocatable addresses and values of listings: offsets in the code and data
+// segments, and values relative to external symbols
+const relMarks = "'\"*"
+
+// absolute drops the "address name" pairs of the symbol table of a listing whose addresses are relocatable
+func absolute(fields []string) []string {
+	var abs []string
+	for i := 0; i+1 < len(fields); i += 2 {
+		if !strings.ContainsAny(fields[i], relMarks) {
+			abs = append(abs, fields[i], fields[i+1])
+		}
+	}
+	if len(fields)%2 != 0 {
+		abs = append(abs, fields[len(fields)-1])
+	}
+	return abs
+}
+
+func (t *Table) parseLine(fields []string) error {
+	if strings.EqualFold(fields[0], "DEFC") {
+		fields = fields[1:]
+	}
+
+	// NAME EQU value, NAME = value, or NAME=value
+	if len(fields) == 1 && strings.Contains(fields[0], "=") {
+		i := strings.IndexByte(fields[0], '=')
+		fields = []string{fields[0][:i], "=", fields[0][i+1:]}
+	}
+	if len(fields) >= 3 && (strings.EqualFold(fields[1], "EQU") || fields[1] == "=") {
+		addr, err := parseNumber(fields[2])
+		if err != nil {
+			return err
+		}
+		t.Add(strings.TrimSuffix(fields[0], ":"), addr)
+		return nil
+	}
+
+	// address name pairs
+	if len(fields)%2 != 0 {
+		return fmt.Errorf("want \"address name\" pairs, got %q", strings.Join(fields, " "))
+	}
+	for i := 0; i < len(fields); i += 2 {
+		addr, err := strconv.ParseUint(fields[i], 16, 16)
+		if err != nil {
+			return fmt.Errorf("invalid address %q", fields[i])
+		}
+		t.Add(fields[i+1], uint16(addr))
+	}
+	return nil
+}
+
+// parseNumber parses a value of a symbol file, which is decimal unless written as an address in hexadecimal with a
+// prefix or suffix
+func parseNumber(s string) (uint16, error) {
+	if strings.Trim(s, "0123456789") != "" {
+		return ParseAddress(s)
+	}
+	v, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return uint16(v), nil
+}
+
+// ParseAddress parses an address written in hexadecimal, like 1A2B, $1A2B, 0x1A2B or 1A2BH, as taken by the
+// commands and the debugger
+func ParseAddress(s string) (uint16, error) {
+	h := strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case strings.HasPrefix(h, "0x"):
+		h = h[2:]
+	case strings.HasPrefix(h, "$"):
+		h = h[1:]
+	case strings.HasSuffix(h, "h"):
+		h = h[:len(h)-1]
+	}
+	v, err := strconv.ParseUint(h, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return uint16(v), nil
+}
+
+// Load reads the symbol file at the given path, or the listing when its extension is .PRN
+func Load(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".prn") {
+		return ReadListing(f)
+	}
+	return Read(f)
+}
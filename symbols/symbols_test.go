@@ -0,0 +1,142 @@
+package symbols
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	for _, tC := range []struct {
+		desc    string
+		file    string
+		want    map[string]uint16
+		wantErr bool
+	}{
+		{
+			"EQU definitions",
+			"START EQU 0100H\nLOOP: equ $0103 ; main loop\nDONE EQU 0x0106\nCOUNT EQU 16",
+			map[string]uint16{"START": 0x100, "LOOP": 0x103, "DONE": 0x106, "COUNT": 16},
+			false,
+		},
+		{
+			"z88dk map",
+			"_main                           = $0103 ; addr, public, , main_c, code_compiler, main.c:5\nDEFC _exit=$0000",
+			map[string]uint16{"_main": 0x103, "_exit": 0},
+			false,
+		},
+		{
+			"CP/M .SYM",
+			"0100 START   0103 LOOP\r\n0106 DONE\r\n\x1a\x1a\x1a",
+			map[string]uint16{"START": 0x100, "LOOP": 0x103, "DONE": 0x106},
+			false,
+		},
+		{
+			"invalid address",
+			"START EQU 10000H",
+			nil,
+			true,
+		},
+		{
+			"incomplete pair",
+			"0100 START 0103",
+			nil,
+			true,
+		},
+	} {
+		t.Run(tC.desc, func(t *testing.T) {
+			table, err := Read(strings.NewReader(tC.file))
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, tC.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if table.Len() != len(tC.want) {
+				t.Errorf("got %d symbols %v, want %d", table.Len(), table.Names(), len(tC.want))
+			}
+			for name, addr := range tC.want {
+				if got, ok := table.Addr(name); !ok || got != addr {
+					t.Errorf("%s: got %04X, want %04X", name, got, addr)
+				}
+				if got, _ := table.Name(addr); got != name {
+					t.Errorf("%04X: got %q, want %q", addr, got, name)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	for _, tC := range []struct {
+		in      string
+		want    uint16
+		wantErr bool
+	}{
+		{"1A2B", 0x1A2B, false},
+		{"$1a2b", 0x1A2B, false},
+		{"0x1A2B", 0x1A2B, false},
+		{"1A2BH", 0x1A2B, false},
+		{" 100 ", 0x100, false},
+		{"10000", 0, true},
+		{"12G", 0, true},
+		{"", 0, true},
+	} {
+		t.Run(tC.in, func(t *testing.T) {
+			got, err := ParseAddress(tC.in)
+			if (err != nil) != tC.wantErr || got != tC.want {
+				t.Errorf("got %04X, %v, want %04X", got, err, tC.want)
+			}
+		})
+	}
+}
+
+func TestReadListing(t *testing.T) {
+	listing := "0100                  1  \tORG 100H\n" +
+		"      = 0003          2  N\tEQU 3\n" +
+		"0100  3E 03           3+ START:\tMVI A,N\n" +
+		"0102  48 45 4C 4C     4  \tDB 'HELLO',0\n" +
+		"0106  4F 00\n" +
+		"\n" +
+		"0003 N               0100 START\n"
+	table, err := ReadListing(strings.NewReader(listing))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(table.Names(), " "); got != "N START" {
+		t.Errorf("got symbols %s, want N START", got)
+	}
+	for addr, want := range map[uint16]string{0x100: "3  START:\tMVI A,N", 0x102: "4  \tDB 'HELLO',0", 0x106: ""} {
+		if got, _ := table.Line(addr); got != want {
+			t.Errorf("%04X: got line %q, want %q", addr, got, want)
+		}
+	}
+}
+
+func TestReadListing_Relocatable(t *testing.T) {
+	listing := "                      1  \tCSEG\n" +
+		"0000' 21 00 00        2  START:\tLXI H,MSG\n" +
+		"0003' CD 00 00        3  \tCALL PRINT\n" +
+		"                      4  \tEXTRN PRINT\n" +
+		"                      5  \tDSEG\n" +
+		"0000\" 01 02 03 04     6  MSG:\tDB 1,2,3,4,5\n" +
+		"0004\" 05\n" +
+		"      = 0003*         7  P\tEQU PRINT+3\n" +
+		"                      8  \tASEG\n" +
+		"0000                  9  \tORG 0\n" +
+		"0000  C3 00 00       10  RESET:\tJMP START\n" +
+		"\n" +
+		"0000\" MSG            0000' START          0000 RESET           0003* P\n"
+	table, err := ReadListing(strings.NewReader(listing))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(table.Names(), " "); got != "RESET" {
+		t.Errorf("got symbols %s, want RESET", got)
+	}
+	for addr, want := range map[uint16]string{0x0000: "10  RESET:\tJMP START", 0x0003: "", 0x0004: ""} {
+		if got, _ := table.Line(addr); got != want {
+			t.Errorf("%04X: got line %q, want %q", addr, got, want)
+		}
+	}
+}